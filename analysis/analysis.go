@@ -0,0 +1,1682 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+// Package analysis loads Go packages and builds the dependency graph of
+// their declarations.
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// builder collects the nodes and links of a Graph from loaded packages
+type builder struct {
+	*graph.Graph
+
+	// closures maps function literals to the IDs of their nodes
+	closures map[*ast.FuncLit]string
+	// inits maps declared init functions to the IDs of their nodes
+	inits map[types.Object]string
+
+	// decls caches the declaration index of every file
+	decls map[*ast.File]*declIndex
+	// files are the syntax files walked for every package, see uniqueSyntax
+	files map[*packages.Package][]*ast.File
+
+	// mu guards Nodes and decls while packages are processed in parallel
+	mu sync.RWMutex
+}
+
+// objID returns the node ID for obj. Declared init functions all share the
+// name "init" and are told apart by their position in the package.
+func (g *builder) objID(obj types.Object) string {
+	if initID, ok := g.inits[obj]; ok {
+		return initID
+	}
+	return id(obj)
+}
+
+// syntax returns the files of pkg to walk for links and metrics
+func (g *builder) syntax(pkg *packages.Package) []*ast.File {
+	return g.files[pkg]
+}
+
+// uniqueSyntax assigns every parsed file to the first package containing it.
+// The test variant of a package, e.g. "p [p.test]", has the same PkgPath and
+// shares the parsed files of the package, which would otherwise be walked
+// twice, doubling the weights of their links.
+func uniqueSyntax(pkgs []*packages.Package) map[*packages.Package][]*ast.File {
+	seen := make(map[*ast.File]bool)
+	files := make(map[*packages.Package][]*ast.File, len(pkgs))
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if !seen[file] {
+				seen[file] = true
+				files[pkg] = append(files[pkg], file)
+			}
+		}
+	}
+	return files
+}
+
+// findContainingNode returns the node of the innermost declaration in the
+// graph that encloses n, or nil if there is none.
+func (g *builder) findContainingNode(pkg *packages.Package, file *ast.File, n ast.Node) *graph.Node {
+	nodes := g.findContainingNodes(pkg, file, n)
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
+
+// findContainingNodes returns the nodes of the innermost declaration in the
+// graph that encloses n. A declaration of several names, e.g. var a, b = x, y,
+// yields the names whose initializer contains n, or all of them for the
+// declared type and for initializers returning multiple values. Parameters,
+// receivers, struct fields and local declarations have no nodes, so n is
+// attributed to the declaration enclosing them.
+func (g *builder) findContainingNodes(pkg *packages.Package, file *ast.File, n ast.Node) []*graph.Node {
+	if n == nil {
+		return nil
+	}
+	for node := range g.declIndex(file).enclosing(n.Pos(), n.End()) {
+		var idents []*ast.Ident
+
+		switch decl := node.(type) {
+		case *ast.FuncLit:
+			// A function literal is enclosed by its parent, not by itself
+			if decl == n {
+				continue
+			}
+			if closureID, ok := g.closures[decl]; ok {
+				return []*graph.Node{g.node(closureID)}
+			}
+			continue
+		case *ast.FuncDecl:
+			idents = []*ast.Ident{decl.Name}
+		case *ast.Field:
+			idents = decl.Names
+		case *ast.TypeSpec:
+			idents = []*ast.Ident{decl.Name}
+		case *ast.ValueSpec:
+			idents = valueSpecNames(decl, n)
+		}
+
+		var nodes []*graph.Node
+		for _, ident := range idents {
+			obj := pkg.TypesInfo.Defs[ident]
+			if obj == nil || !hasObjectNode(obj) {
+				continue
+			}
+			if node := g.node(g.objID(obj)); node != nil {
+				nodes = append(nodes, node)
+			}
+		}
+		if len(nodes) > 0 {
+			return nodes
+		}
+	}
+
+	return nil
+}
+
+// valueSpecNames returns the names declared by spec that n belongs to
+func valueSpecNames(spec *ast.ValueSpec, n ast.Node) []*ast.Ident {
+	for i, name := range spec.Names {
+		if n.Pos() >= name.Pos() && n.End() <= name.End() {
+			return spec.Names[i : i+1]
+		}
+	}
+	if len(spec.Values) == len(spec.Names) {
+		for i, value := range spec.Values {
+			if n.Pos() >= value.Pos() && n.End() <= value.End() {
+				return spec.Names[i : i+1]
+			}
+		}
+	}
+	return spec.Names
+}
+
+// Options configures Analyze
+type Options struct {
+	// CallGraph selects the SSA call graph algorithm used to add call links,
+	// or CallGraphNone to only collect references from the syntax tree.
+	CallGraph string
+	// Closures emits function literals as child nodes of their enclosing
+	// declaration instead of attributing their references to it.
+	Closures bool
+	// Instances emits nodes for concrete instantiations of generic functions
+	// and types.
+	Instances bool
+	// IncludeStd emits leaf nodes for standard library symbols referenced by
+	// the analyzed packages.
+	IncludeStd bool
+	// IncludeDeps is the number of import hops into third-party packages
+	// whose symbols are included. Dependencies closer than the last hop are
+	// analyzed like the initial packages, symbols referenced in the last hop
+	// become leaf nodes.
+	IncludeDeps int
+	// Tags is a comma-separated list of build tags to consider satisfied
+	// while loading packages.
+	Tags string
+	// GOOS and GOARCH override the target platform while loading packages.
+	GOOS, GOARCH string
+	// Env lists KEY=VALUE environment variables for the go command, in
+	// addition to the environment of the process, e.g. CGO_ENABLED=0.
+	Env []string
+	// ExcludeTests leaves out test files while loading packages, so the
+	// graph only contains the code that is built.
+	ExcludeTests bool
+	// Platforms is a comma-separated list of GOOS/GOARCH pairs to analyze
+	// one after another, producing the union of the graphs.
+	Platforms string
+	// Workspace adds all modules of the active go.work file to the analyzed
+	// packages.
+	Workspace bool
+	// ExportedOnly drops all nodes that are not part of the public API.
+	ExportedOnly bool
+	// ExcludeGenerated drops all nodes declared in generated files.
+	ExcludeGenerated bool
+	// Include, if set, drops all nodes whose ID and package path it does
+	// not match, Exclude all nodes whose ID or package path it matches.
+	Include, Exclude *regexp.Regexp
+	// Filter, if set, drops all nodes for which it returns false, along with
+	// their links.
+	Filter func(node *graph.Node) bool
+	// Classifiers assign every node the group returned by the first of them
+	// that classifies it.
+	Classifiers []Classifier
+	// FileNodes emits a node for every source file linked to the
+	// declarations in it.
+	FileNodes bool
+	// Hierarchy emits nodes for all analyzed packages and the directories
+	// above them, linked to what they contain.
+	Hierarchy bool
+	// UnsafeOnly drops all nodes that neither use unsafe or cgo nor depend
+	// on a node that does.
+	UnsafeOnly bool
+	// BestEffort emits the declarations of packages without type
+	// information based on their syntax alone.
+	BestEffort bool
+	// PositionStrings emits node positions as strings instead of objects.
+	PositionStrings bool
+	// TrimPrefix shortens import paths in node IDs, by default those of the
+	// analyzed modules.
+	TrimPrefix TrimPrefix
+	// Condense collapses dependency cycles into component nodes
+	Condense bool
+	// Reduce removes links implied by transitivity
+	Reduce bool
+	// Centrality computes the PageRank and betweenness of every node
+	Centrality bool
+	// TestedBy records the tests exercising every node
+	TestedBy bool
+	// Plugins are the command lines of external analyzers contributing
+	// nodes, links and attributes to the graph.
+	Plugins []string
+	// Vulns is a file of govulncheck -json output, or "-" to run
+	// govulncheck, whose findings are marked in the graph.
+	Vulns string
+	// Focus, if set, restricts the graph to the nodes with the given IDs and
+	// the nodes at most FocusDepth links away from them in either direction,
+	// like Graph.Subgraph. Metrics are computed on the whole graph.
+	Focus      []string
+	FocusDepth int
+	// MinWeight and TopLinks drop light links, see Graph.PruneLinks.
+	MinWeight, TopLinks int
+	// MaxNodes, if positive, collapses nodes into summary nodes until the
+	// graph has at most MaxNodes nodes, see Graph.Collapse.
+	MaxNodes int
+	// ShortIDs replaces node IDs by short hashes with a label table mapping
+	// them back to the full IDs.
+	ShortIDs bool
+	// Strict fails the analysis if any package has load or type errors
+	// instead of marking the nodes of broken packages.
+	Strict bool
+	// Rev is a git revision to check out into a temporary worktree and
+	// analyze instead of the working tree.
+	Rev string
+	// ChangedSince, if set, is a git revision to restrict the analysis to
+	// the packages changed since and the packages depending on them.
+	ChangedSince string
+	// Dir is the directory package paths are resolved in, by default the
+	// working directory.
+	Dir string
+	// Overlay maps absolute file paths to contents replacing those on disk,
+	// e.g. of unsaved editor buffers.
+	Overlay map[string][]byte
+	// Compact drops the Object and Package of the nodes once the graph is
+	// built, so the loaded packages can be garbage collected, and shares the
+	// strings repeated across nodes, e.g. file names.
+	Compact bool
+	// Progress, if set, receives progress reports of the analysis, one per
+	// line.
+	Progress io.Writer
+	// Shard, if set, restricts the analysis to one of several disjoint sets
+	// of the packages matching the paths, to analyze them in separate
+	// processes and merge the graphs.
+	Shard Shard
+	// Load, if set, loads packages instead of packages.Load, e.g. from a
+	// cache of previously loaded packages. The packages must not be
+	// modified.
+	Load func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error)
+
+	// leafPkgs are the packages whose symbols are emitted as leaf nodes when
+	// referenced, like those of dependencies, used by Walk and for shards
+	leafPkgs map[string]bool
+}
+
+// packagesConfig returns the configuration packages are loaded with
+func (opts *Options) packagesConfig() *packages.Config {
+	cfg := &packages.Config{
+		Dir:     opts.Dir,
+		Tests:   !opts.ExcludeTests,
+		Overlay: opts.Overlay,
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedModule,
+	}
+	if len(opts.Env) > 0 || opts.GOOS != "" || opts.GOARCH != "" {
+		cfg.Env = append(os.Environ(), opts.Env...)
+		if opts.GOOS != "" {
+			cfg.Env = append(cfg.Env, "GOOS="+opts.GOOS)
+		}
+		if opts.GOARCH != "" {
+			cfg.Env = append(cfg.Env, "GOARCH="+opts.GOARCH)
+		}
+	}
+	if opts.Tags != "" {
+		cfg.BuildFlags = append(cfg.BuildFlags, "-tags="+opts.Tags)
+	}
+	if opts.CallGraph != CallGraphNone || opts.IncludeDeps > 1 {
+		// SSA construction and analysis of dependencies need type
+		// information and syntax for all dependencies
+		cfg.Mode |= packages.NeedDeps
+	}
+	return cfg
+}
+
+// Analyze loads the packages matching paths and returns the graph of their
+// declarations and the links between them, as configured by opts. A nil opts
+// analyzes with the defaults of the command line.
+func Analyze(opts *Options, paths ...string) (*graph.Graph, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.Rev != "" {
+		return analyzeRevision(opts, paths...)
+	}
+	if slices.ContainsFunc(paths, isVersioned) {
+		return analyzeVersions(opts, paths...)
+	}
+	if opts.Platforms != "" {
+		return analyzePlatforms(opts, paths...)
+	}
+	if opts.ChangedSince != "" {
+		return analyzeChanged(opts, paths...)
+	}
+	if opts.Shard.Count > 0 {
+		return analyzeShard(opts, paths...)
+	}
+
+	if opts.Workspace {
+		patterns, err := workspacePatterns(opts.Dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, patterns...)
+	}
+
+	if opts.CallGraph != CallGraphNone && !slices.Contains(CallGraphModes, opts.CallGraph) {
+		return nil, fmt.Errorf("unknown call graph algorithm %q", opts.CallGraph)
+	}
+	for _, env := range opts.Env {
+		if !strings.Contains(env, "=") {
+			return nil, fmt.Errorf("invalid environment variable %q, expected KEY=VALUE", env)
+		}
+	}
+
+	prog := &progress{w: opts.Progress}
+	prog.report("Loading packages...")
+	cfg := opts.packagesConfig()
+	if opts.Progress != nil {
+		var parsed atomic.Int64
+		cfg.ParseFile = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			prog.update("Parsed %d files", parsed.Add(1))
+			return parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments)
+		}
+	}
+	load := packages.Load
+	if opts.Load != nil {
+		load = opts.Load
+	}
+	pkgs, err := load(cfg, paths...)
+	if err != nil {
+		return nil, err
+	}
+	prog.report("Loaded %d packages", len(pkgs))
+	broken := brokenPackages(pkgs)
+	if opts.Strict && len(broken) > 0 {
+		return nil, broken
+	}
+
+	var depDepths map[string]int
+	if opts.IncludeDeps > 0 {
+		var deps []*packages.Package
+		depDepths, deps = dependencyDepths(pkgs, opts.IncludeDeps)
+		pkgs = append(pkgs, deps...)
+	}
+
+	g := &builder{
+		Graph: &graph.Graph{
+			Nodes:           make(map[string]*graph.Node),
+			PositionStrings: opts.PositionStrings,
+		},
+		closures: make(map[*ast.FuncLit]string),
+		inits:    make(map[types.Object]string),
+		decls:    make(map[*ast.File]*declIndex),
+		files:    uniqueSyntax(pkgs),
+	}
+
+	links := make(graph.LinkSet)
+
+	// Collect nodes, building the nodes of the package scopes in parallel
+	// and adding them in package order
+	scopeNodes := make([][]graph.Node, len(pkgs))
+	forEachPackage(pkgs, func(i int, pkg *packages.Package) {
+		if strings.HasSuffix(pkg.PkgPath, ".test") || lacksTypes(pkg) {
+			return
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			// C symbols are represented by the synthetic C package
+			if isCgoGenerated(pkg, obj) {
+				continue
+			}
+
+			for _, node := range objNodes(pkg, obj) {
+				node.External = depDepths[pkg.PkgPath] > 0
+				node.Module = modulePath(pkg)
+				node.Broken = len(broken[pkg.ID]) > 0
+				scopeNodes[i] = append(scopeNodes[i], node)
+			}
+		}
+	})
+	for i, pkg := range pkgs {
+		if strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue
+		}
+		// Packages that failed to load, e.g. because cgo preprocessing failed,
+		// may lack type information
+		if lacksTypes(pkg) {
+			if opts.BestEffort {
+				for _, node := range syntaxNodes(pkg, links) {
+					g.Nodes[node.Id] = node
+				}
+			}
+			continue
+		}
+		for _, node := range scopeNodes[i] {
+			g.Nodes[node.Id] = &node
+		}
+
+		for obj, node := range initNodes(pkg) {
+			node.Module = modulePath(pkg)
+			node.Broken = len(broken[pkg.ID]) > 0
+			g.Nodes[node.Id] = node
+			g.inits[obj] = node.Id
+		}
+
+		for _, node := range constGroupNodes(pkg, g.Nodes) {
+			node.Module = modulePath(pkg)
+			node.Broken = len(broken[pkg.ID]) > 0
+			g.Nodes[node.Id] = node
+		}
+	}
+
+	// Walk and shards analyze a part of the packages, with the declarations
+	// of the other packages they import as leaf nodes
+	for _, pkg := range walkedImports(pkgs, opts.leafPkgs) {
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			for _, node := range objNodes(pkg, scope.Lookup(name)) {
+				node.External = true
+				g.Nodes[node.Id] = &node
+			}
+		}
+	}
+
+	prog.report("Collected %d nodes", len(g.Nodes))
+
+	g.markExported()
+	g.markGenerated()
+
+	// Collect const group links
+	for _, node := range g.Nodes {
+		if node.Kind == graph.KindConst && node.Parent != "" {
+			links.Insert(node.Id, node.Parent, graph.LinkParent)
+		}
+	}
+
+	// Collect generic instance nodes
+	if opts.Instances {
+		for _, pkg := range pkgs {
+			if pkg.TypesInfo == nil {
+				continue
+			}
+			g.addInstanceNodes(pkg, links)
+		}
+	}
+
+	// Collect closure nodes
+	if opts.Closures {
+		for _, pkg := range pkgs {
+			if strings.HasSuffix(pkg.PkgPath, ".test") || pkg.TypesInfo == nil {
+				continue
+			}
+			for _, file := range g.syntax(pkg) {
+				g.addClosureNodes(pkg, file, links)
+			}
+		}
+	}
+
+	// Collect function metrics
+	for _, pkg := range pkgs {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		for _, file := range g.syntax(pkg) {
+			g.addMetrics(pkg, file)
+		}
+	}
+
+	// Collect usage links, in parallel since this is the bulk of the work.
+	// Nodes may only be read and added through node and addNode until all
+	// packages are done.
+	pkgLinks := make([]graph.LinkSet, len(pkgs))
+	var analyzed, linkCount atomic.Int64
+	forEachPackage(pkgs, func(i int, pkg *packages.Package) {
+		if pkg.TypesInfo == nil {
+			return
+		}
+		links := make(graph.LinkSet)
+		pkgLinks[i] = links
+		defer func() {
+			n := linkCount.Add(int64(len(links)))
+			g.mu.RLock()
+			nodes := len(g.Nodes)
+			g.mu.RUnlock()
+			prog.update("Analyzed %d/%d packages, %d nodes, %d links so far", analyzed.Add(1), len(pkgs), nodes, n)
+		}()
+		for _, file := range g.syntax(pkg) {
+			callees := calleeIdents(file)
+			asserted := assertedIdents(file)
+			converted := conversionIdents(pkg, file)
+			constructed := literalTypeIdents(file)
+			deferred := goDeferIdents(file)
+			ast.Inspect(file, func(n ast.Node) bool {
+				parentNodes := g.findContainingNodes(pkg, file, n)
+				if len(parentNodes) == 0 {
+					return true
+				}
+				insert := func(to, kind string) {
+					for _, parentNode := range parentNodes {
+						links.Insert(parentNode.Id, to, kind)
+					}
+				}
+
+				if e, ok := n.(*ast.SelectorExpr); ok {
+					// Fields are linked through the type of e.X, methods
+					// are linked once through e.Sel below
+					if sel, ok := pkg.TypesInfo.Selections[e]; ok && sel.Kind() == types.FieldVal {
+						refObj := sel.Obj()
+						ts := underlyingTypes(pkg.TypesInfo.TypeOf(e.X))
+						for _, typ := range ts {
+							named, ok := typ.(*types.Named)
+							if ok {
+								typ = named.Underlying()
+								if _, ok = typ.(*types.Struct); ok {
+									if refEntity := g.node(id(named.Obj())); refEntity != nil {
+										insert("("+refEntity.Id+")."+refObj.Name(), graph.LinkReference)
+									}
+								}
+							}
+						}
+					}
+
+					if cgoNode := g.cgoSelectorNode(pkg, e, callees); cgoNode != nil {
+						insert(cgoNode.Id, graph.LinkReference)
+					}
+
+					// Promoted fields and methods are used through the embedded
+					// fields they are promoted from
+					if sel, ok := pkg.TypesInfo.Selections[e]; ok {
+						for _, fieldID := range promotionPath(sel) {
+							insert(fieldID, graph.LinkReference)
+						}
+					}
+				}
+
+				if lit, ok := n.(*ast.CompositeLit); ok {
+					typ := pkg.TypesInfo.TypeOf(lit)
+					if ptr, ok := typ.(*types.Pointer); ok {
+						typ = ptr.Elem()
+					}
+					if named, ok := types.Unalias(typ).(*types.Named); ok {
+						// Literals with elided types, e.g. the elements of
+						// []T{{...}}, have no identifier naming the type
+						if typeNode := g.node(named.String()); typeNode != nil && lit.Type == nil {
+							insert(typeNode.Id, graph.LinkConstructs)
+						}
+						if _, ok := named.Underlying().(*types.Struct); ok {
+							if structNode := g.node(id(named.Obj())); structNode != nil {
+								for _, elt := range lit.Elts {
+									kv, ok := elt.(*ast.KeyValueExpr)
+									if !ok {
+										continue
+									}
+									if key, ok := kv.Key.(*ast.Ident); ok {
+										insert("("+structNode.Id+")."+key.Name, graph.LinkReference)
+									}
+								}
+							}
+						}
+					}
+				}
+
+				if ident, ok := n.(*ast.Ident); ok {
+					if refObj := pkg.TypesInfo.Uses[ident]; refObj != nil && hasObjectNode(refObj) {
+						refEntity := g.node(instanceNodeID(pkg, ident, refObj))
+						if refEntity == nil {
+							refEntity = g.node(id(refObj))
+						}
+						// Methods of instantiated types belong to the generic method
+						if fn, ok := refObj.(*types.Func); ok && refEntity == nil {
+							refEntity = g.node(id(fn.Origin()))
+						}
+						if refEntity == nil && refObj.Pkg() == pkg.Types && isCgoGenerated(pkg, refObj) {
+							refEntity = g.cgoObjectNode(refObj)
+						}
+						if refEntity == nil && refObj.Pkg() != nil {
+							refPkg := refObj.Pkg().Path()
+							if opts.IncludeStd && isStdPkg(refPkg) || depDepths[refPkg] > 0 || opts.leafPkgs[refPkg] && refObj.Pkg() != pkg.Types {
+								refEntity = g.externalNode(refObj)
+							}
+						}
+						if refEntity != nil {
+							kind := graph.LinkReference
+							// Functions and methods that are not called directly are
+							// passed around as values, e.g. callbacks
+							if _, ok := refObj.(*types.Func); ok && !callees[ident] {
+								kind = graph.LinkValue
+							}
+							if _, ok := refObj.(*types.TypeName); ok && asserted[ident] {
+								kind = graph.LinkAsserts
+							}
+							if _, ok := refObj.(*types.TypeName); ok && converted[ident] {
+								kind = graph.LinkConvertsTo
+							}
+							if _, ok := refObj.(*types.TypeName); ok && constructed[ident] {
+								kind = graph.LinkConstructs
+							}
+							if stmtKind, ok := deferred[ident]; ok && (kind == graph.LinkReference || kind == graph.LinkValue) {
+								kind = stmtKind
+							}
+							insert(refEntity.Id, kind)
+						}
+					}
+				}
+				return true
+			})
+		}
+	})
+	for _, pkgLinks := range pkgLinks {
+		for link, n := range pkgLinks {
+			links[link] += n
+		}
+	}
+	prog.report("Collected %d usage links", len(links))
+
+	// Collect method and field links
+	for _, node := range g.Nodes {
+		// Only type declarations own methods and fields, not variables of
+		// a named type
+		if node.Object == nil || node.Kind != graph.KindType {
+			continue
+		}
+		if alias, ok := node.Object.Type().(*types.Alias); ok {
+			for _, typ := range underlyingTypes(alias.Rhs()) {
+				if target, ok := g.Nodes[typ.String()]; ok {
+					links.Insert(node.Id, target.Id, graph.LinkAliases)
+				}
+			}
+		}
+		if named, ok := node.Object.Type().(*types.Named); ok {
+			for method := range named.Methods() {
+				links.Insert(id(method), node.Id, graph.LinkMethodOf)
+			}
+			switch u := named.Underlying().(type) {
+			case *types.Interface:
+				for method := range u.ExplicitMethods() {
+					links.Insert(id(method), node.Id, graph.LinkMethodOf)
+				}
+				for embedded := range u.EmbeddedTypes() {
+					embeddedId := embedded.String()
+					if _, ok := g.Nodes[embeddedId]; !ok {
+						continue
+					}
+					links.Insert(node.Id, embeddedId, graph.LinkEmbeds)
+				}
+			case *types.Struct:
+				for field := range u.Fields() {
+					types := underlyingTypes(field.Type())
+					for _, typ := range types {
+						if typeNode, ok := g.Nodes[typ.String()]; ok {
+							links.Insert("("+node.Id+")."+field.Name(), typeNode.Id, graph.LinkFieldType)
+							if field.Embedded() {
+								links.Insert(node.Id, typeNode.Id, graph.LinkEmbeds)
+							}
+						}
+					}
+					links.Insert("("+node.Id+")."+field.Name(), node.Id, graph.LinkParent)
+				}
+			}
+		}
+	}
+
+	// Collect side-effect import links
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.PkgPath, ".test") || pkg.TypesInfo == nil {
+			continue
+		}
+		g.addBlankImportEdges(pkg, links)
+	}
+
+	// Collect type parameter constraint links
+	g.addConstraintEdges(links)
+
+	// Collect interface satisfaction links
+	g.addImplementsEdges(links)
+
+	// Collect example links
+	g.addExampleEdges(links)
+
+	// Collect file nodes
+	if opts.FileNodes {
+		for _, pkg := range pkgs {
+			if strings.HasSuffix(pkg.PkgPath, ".test") {
+				continue
+			}
+			g.addFileNodes(pkg, links)
+		}
+	}
+
+	// Collect package hierarchy nodes
+	if opts.Hierarchy {
+		g.addHierarchy(pkgs, links)
+	}
+
+	// Collect directives
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.PkgPath, ".test") || pkg.TypesInfo == nil {
+			continue
+		}
+		for _, file := range g.syntax(pkg) {
+			g.addDirectives(pkg, file, links)
+		}
+	}
+
+	// Mark declarations using unsafe or cgo
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.PkgPath, ".test") || pkg.TypesInfo == nil {
+			continue
+		}
+		g.markUnsafe(pkg)
+	}
+
+	// Mark types used through reflection
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.PkgPath, ".test") || pkg.TypesInfo == nil {
+			continue
+		}
+		g.markReflected(pkg)
+	}
+
+	// Collect dependency injection links
+	var di diContainer
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.PkgPath, ".test") || pkg.TypesInfo == nil {
+			continue
+		}
+		g.collectDI(pkg, &di)
+	}
+	g.addDIEdges(&di, links)
+
+	// Collect call links
+	if opts.CallGraph != CallGraphNone {
+		prog.report("Building %s call graph...", opts.CallGraph)
+		cg, err := buildCallGraph(opts.CallGraph, pkgs)
+		if err != nil {
+			return nil, err
+		}
+		g.addCallEdges(cg, links)
+	}
+
+	for nodeID, node := range g.Nodes {
+		if opts.ExportedOnly && !node.Exported && node.Kind != graph.KindPackage && node.Kind != graph.KindFile {
+			delete(g.Nodes, nodeID)
+		}
+		if opts.ExcludeGenerated && node.Generated {
+			delete(g.Nodes, nodeID)
+		}
+		if opts.Include != nil && !opts.Include.MatchString(nodeID) && !opts.Include.MatchString(node.Pkg) {
+			delete(g.Nodes, nodeID)
+		}
+		if opts.Exclude != nil && (opts.Exclude.MatchString(nodeID) || opts.Exclude.MatchString(node.Pkg)) {
+			delete(g.Nodes, nodeID)
+		}
+		if opts.Filter != nil && !opts.Filter(node) {
+			delete(g.Nodes, nodeID)
+		}
+	}
+
+	g.classify(opts.Classifiers)
+
+	for link, weight := range links {
+		if _, ok := g.Nodes[link.From]; !ok {
+			continue
+		}
+		if _, ok := g.Nodes[link.To]; !ok {
+			continue
+		}
+		g.Links = append(g.Links, graph.Link{From: link.From, To: link.To, Kind: link.Kind, Weight: weight})
+	}
+
+	if opts.UnsafeOnly {
+		g.keepUnsafe()
+	}
+
+	for _, plugin := range opts.Plugins {
+		if err := g.runPlugin(plugin, opts.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	g.AddCohesion()
+	if opts.Vulns != "" {
+		findings, err := loadVulns(opts.Vulns, opts.Dir, paths)
+		if err != nil {
+			return nil, err
+		}
+		g.markVulns(findings)
+	}
+	g.TrimPrefixes(opts.TrimPrefix.prefixes(pkgs))
+	if opts.TestedBy {
+		g.AddTestedBy()
+	}
+	if opts.Condense {
+		g.Condense()
+	}
+	if opts.Reduce {
+		g.Reduce()
+	}
+	g.AddFanMetrics()
+	if opts.Centrality {
+		g.AddCentrality()
+	}
+	if len(opts.Focus) > 0 {
+		focused, err := focus(g.Graph, opts.Focus, opts.FocusDepth)
+		if err != nil {
+			return nil, err
+		}
+		g.Graph = focused
+	}
+	g.PruneLinks(opts.MinWeight, opts.TopLinks)
+	g.Collapse(opts.MaxNodes)
+	if opts.ShortIDs {
+		g.ShortenIDs()
+	}
+	if opts.Compact {
+		g.compact()
+	}
+	prog.report("Built graph with %d nodes and %d links", len(g.Nodes), len(g.Links))
+
+	return g.Graph, nil
+}
+
+// focus returns the subgraph of g around the nodes with the given IDs, which
+// must be part of g
+func focus(g *graph.Graph, ids []string, depth int) (*graph.Graph, error) {
+	for _, nodeID := range ids {
+		if _, ok := g.Nodes[nodeID]; !ok {
+			return nil, fmt.Errorf("unknown focus node %q", nodeID)
+		}
+	}
+	return g.Subgraph(ids, depth), nil
+}
+
+// calleeIdents returns the identifiers in file that name the function of a
+// call expression, as opposed to functions that are used as values.
+func calleeIdents(file *ast.File) map[*ast.Ident]bool {
+	idents := make(map[*ast.Ident]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fun := ast.Unparen(call.Fun)
+		// Explicit instantiations of generic functions, e.g. f[int](x)
+		switch f := fun.(type) {
+		case *ast.IndexExpr:
+			fun = ast.Unparen(f.X)
+		case *ast.IndexListExpr:
+			fun = ast.Unparen(f.X)
+		}
+		switch f := fun.(type) {
+		case *ast.Ident:
+			idents[f] = true
+		case *ast.SelectorExpr:
+			idents[f.Sel] = true
+		}
+		return true
+	})
+	return idents
+}
+
+// assertedIdents returns the identifiers in file that are part of the type of
+// a type assertion, x.(T), or of a case of a type switch.
+func assertedIdents(file *ast.File) map[*ast.Ident]bool {
+	idents := make(map[*ast.Ident]bool)
+	collect := func(expr ast.Expr) {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				idents[ident] = true
+			}
+			return true
+		})
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.TypeAssertExpr:
+			// The type is nil in the x.(type) guard of a type switch
+			if n.Type != nil {
+				collect(n.Type)
+			}
+		case *ast.TypeSwitchStmt:
+			for _, stmt := range n.Body.List {
+				for _, expr := range stmt.(*ast.CaseClause).List {
+					collect(expr)
+				}
+			}
+		}
+		return true
+	})
+	return idents
+}
+
+// conversionIdents returns the identifiers in file that are part of the
+// target type of an explicit conversion, e.g. T(x), (*T)(x) or []T(x).
+func conversionIdents(pkg *packages.Package, file *ast.File) map[*ast.Ident]bool {
+	idents := make(map[*ast.Ident]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !pkg.TypesInfo.Types[call.Fun].IsType() {
+			return true
+		}
+		ast.Inspect(call.Fun, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				idents[ident] = true
+			}
+			return true
+		})
+		return true
+	})
+	return idents
+}
+
+// literalTypeIdents returns the identifiers in file that name the type of a
+// composite literal, e.g. T in T{...}, pkg.T{...} or T[int]{...}.
+func literalTypeIdents(file *ast.File) map[*ast.Ident]bool {
+	idents := make(map[*ast.Ident]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok || lit.Type == nil {
+			return true
+		}
+		typ := ast.Unparen(lit.Type)
+		switch t := typ.(type) {
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		}
+		switch t := typ.(type) {
+		case *ast.Ident:
+			idents[t] = true
+		case *ast.SelectorExpr:
+			idents[t.Sel] = true
+		}
+		return true
+	})
+	return idents
+}
+
+// goDeferIdents returns the identifiers in file that occur inside go and
+// defer statements, including the bodies of function literals started or
+// deferred there, mapped to the go or defer link kind.
+func goDeferIdents(file *ast.File) map[*ast.Ident]string {
+	idents := make(map[*ast.Ident]string)
+	collect := func(call *ast.CallExpr, kind string) {
+		ast.Inspect(call, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				idents[ident] = kind
+			}
+			return true
+		})
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			collect(n.Call, graph.LinkGo)
+		case *ast.DeferStmt:
+			collect(n.Call, graph.LinkDefer)
+		}
+		return true
+	})
+	return idents
+}
+
+// addClosureNodes emits a node for every function literal in file, named
+// after its enclosing node with a "$N" suffix numbered in source order like
+// SSA function names (e.g. pkg.Foo$1, pkg.Foo$1$1), and links it to its
+// parent.
+func (g *builder) addClosureNodes(pkg *packages.Package, file *ast.File, links graph.LinkSet) {
+	counts := make(map[string]int)
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		if _, ok := g.closures[lit]; ok {
+			return true
+		}
+		parent := g.findContainingNode(pkg, file, lit)
+		if parent == nil {
+			return true
+		}
+
+		counts[parent.Id]++
+		suffix := "$" + strconv.Itoa(counts[parent.Id])
+		var signature string
+		if sig, ok := pkg.TypesInfo.TypeOf(lit).(*types.Signature); ok {
+			signature = funcSignature("", sig, types.RelativeTo(pkg.Types))
+		}
+		node := &graph.Node{
+			Package:   pkg,
+			Kind:      graph.KindFunc,
+			Type:      graph.FuncClosure,
+			Id:        parent.Id + suffix,
+			Parent:    parent.Id,
+			LocalName: parent.LocalName + suffix,
+			Pkg:       parent.Pkg,
+			Module:    parent.Module,
+			Position:  sourceRange(pkg, lit.Pos(), lit.End()),
+			Test:      parent.Test,
+			Generated: parent.Generated,
+			Broken:    parent.Broken,
+			Signature: signature,
+		}
+		g.Nodes[node.Id] = node
+		g.closures[lit] = node.Id
+		links.Insert(node.Id, parent.Id, graph.LinkParent)
+		return true
+	})
+}
+
+// addBlankImportEdges links pkg to the init functions of the packages it
+// imports only for their side effects, e.g. import _ "image/png". Since no
+// declaration of pkg uses those packages, the links start at a node for pkg
+// itself. Imported packages without init functions in the graph are skipped.
+func (g *builder) addBlankImportEdges(pkg *packages.Package, links graph.LinkSet) {
+	for _, file := range g.syntax(pkg) {
+		for _, imp := range file.Imports {
+			if imp.Name == nil || imp.Name.Name != "_" {
+				continue
+			}
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			imported, ok := pkg.Imports[path]
+			if !ok {
+				continue
+			}
+			for _, initID := range g.inits {
+				if g.Nodes[initID].Pkg != imported.PkgPath {
+					continue
+				}
+				if _, ok := g.Nodes[pkg.PkgPath]; !ok {
+					g.Nodes[pkg.PkgPath] = &graph.Node{
+						Kind:      graph.KindPackage,
+						Id:        pkg.PkgPath,
+						LocalName: pkg.PkgPath,
+						Pkg:       pkg.PkgPath,
+						Module:    modulePath(pkg),
+					}
+				}
+				links.Insert(pkg.PkgPath, initID, graph.LinkImports)
+			}
+		}
+	}
+}
+
+// addImplementsEdges links every concrete named type to the interfaces in
+// the graph that it or a pointer to it implements. Empty interfaces and
+// generic types are skipped since they would match everything or need
+// instantiation.
+func (g *builder) addImplementsEdges(links graph.LinkSet) {
+	var ifaces []*graph.Node
+	for _, node := range g.Nodes {
+		if node.Object == nil || node.Kind != graph.KindType || node.Type != graph.TypeInterface {
+			continue
+		}
+		named, ok := node.Object.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue
+		}
+		if u := named.Underlying().(*types.Interface); u.NumMethods() == 0 || !u.IsMethodSet() {
+			continue
+		}
+		ifaces = append(ifaces, node)
+	}
+
+	for _, node := range g.Nodes {
+		if node.Object == nil || node.Kind != graph.KindType || node.Type == graph.TypeInterface {
+			continue
+		}
+		named, ok := node.Object.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue
+		}
+		ptr := types.NewPointer(named)
+		for _, iface := range ifaces {
+			u := iface.Object.Type().Underlying().(*types.Interface)
+			if types.Implements(named, u) || types.Implements(ptr, u) {
+				links.Insert(node.Id, iface.Id, graph.LinkImplements)
+			}
+		}
+	}
+}
+
+func objNodes(pkg *packages.Package, obj types.Object) []graph.Node {
+	filename := pkg.Fset.Position(obj.Pos()).Filename
+	start, end := getObjectRange(pkg, obj)
+	pkgName := pkg.Name
+	isTest := strings.HasSuffix(filename, "_test.go") || strings.HasSuffix(pkgName, "_test")
+	switch t := obj.(type) {
+	case *types.Func:
+		funcType := graph.FuncBasic
+		if isTest {
+			funcType = testFuncType(t)
+		}
+		return []graph.Node{{
+			Object:    obj,
+			Package:   pkg,
+			Kind:      graph.KindFunc,
+			Type:      funcType,
+			Id:        id(t),
+			LocalName: t.Name(),
+			Pkg:       obj.Pkg().Path(),
+			Position:  sourceRange(pkg, start, end),
+			Test:      isTest,
+			Signature: funcSignature(t.Name(), t.Signature(), types.RelativeTo(t.Pkg())),
+		}}
+	case *types.TypeName:
+		// type foo = bar
+		if t.IsAlias() {
+			return []graph.Node{{
+				Object:    obj,
+				Package:   pkg,
+				Kind:      graph.KindType,
+				Type:      graph.TypeAlias,
+				Id:        id(t),
+				LocalName: t.Name(),
+				Pkg:       obj.Pkg().Path(),
+				Position:  sourceRange(pkg, start, end),
+				Test:      isTest,
+			}}
+		}
+
+		var nodes []graph.Node
+
+		switch u := t.Type().Underlying().(type) {
+		// type foo struct{}
+		case *types.Struct:
+			node := graph.Node{
+				Object:    obj,
+				Package:   pkg,
+				Kind:      graph.KindType,
+				Type:      graph.TypeStruct,
+				Id:        id(t),
+				LocalName: t.Name(),
+				Pkg:       obj.Pkg().Path(),
+				Position:  sourceRange(pkg, start, end),
+				Test:      isTest,
+			}
+			nodes = append(nodes, node)
+
+			for field := range u.Fields() {
+				start, end := getObjectRange(pkg, field)
+				nodes = append(nodes, graph.Node{
+					Object:    field,
+					Package:   pkg,
+					Kind:      graph.KindVar,
+					Type:      graph.VarField,
+					Id:        "(" + id(t) + ")." + field.Name(),
+					Parent:    node.Id,
+					LocalName: t.Name() + "." + field.Name(),
+					Pkg:       obj.Pkg().Path(),
+					Position:  sourceRange(pkg, start, end),
+					Test:      isTest,
+				})
+			}
+		// type foo interface{}
+		case *types.Interface:
+			node := graph.Node{
+				Object:    obj,
+				Package:   pkg,
+				Kind:      graph.KindType,
+				Type:      graph.TypeInterface,
+				Id:        id(t),
+				LocalName: t.Name(),
+				Pkg:       obj.Pkg().Path(),
+				Position:  sourceRange(pkg, start, end),
+				Test:      isTest,
+			}
+			nodes = append(nodes, node)
+
+			for method := range u.ExplicitMethods() {
+				start, end := getObjectRange(pkg, method)
+				nodes = append(nodes, graph.Node{
+					Object:    method,
+					Package:   pkg,
+					Kind:      graph.KindFunc,
+					Type:      graph.FuncMethod,
+					Id:        id(method),
+					Parent:    node.Id,
+					LocalName: t.Name() + "." + method.Name(),
+					Pkg:       obj.Pkg().Path(),
+					Position:  sourceRange(pkg, start, end),
+					Test:      isTest,
+					Signature: funcSignature(method.Name(), method.Signature(), types.RelativeTo(t.Pkg())),
+				})
+			}
+		// type foo bar
+		case *types.Basic:
+			nodes = append(nodes, graph.Node{
+				Object:    obj,
+				Package:   pkg,
+				Kind:      graph.KindType,
+				Type:      graph.TypeBasic,
+				Id:        id(t),
+				LocalName: t.Name(),
+				Pkg:       obj.Pkg().Path(),
+				Position:  sourceRange(pkg, start, end),
+				Test:      isTest,
+			})
+		case *types.Signature:
+			nodes = append(nodes, graph.Node{
+				Object:    obj,
+				Package:   pkg,
+				Kind:      graph.KindType,
+				Type:      graph.TypeFunc,
+				Id:        id(t),
+				LocalName: t.Name(),
+				Pkg:       obj.Pkg().Path(),
+				Position:  sourceRange(pkg, start, end),
+				Test:      isTest,
+			})
+		default:
+			nodes = append(nodes, graph.Node{
+				Object:    obj,
+				Package:   pkg,
+				Kind:      graph.KindType,
+				Type:      graph.TypeName,
+				Id:        id(t),
+				LocalName: t.Name(),
+				Pkg:       obj.Pkg().Path(),
+				Position:  sourceRange(pkg, start, end),
+				Test:      isTest,
+			})
+		}
+
+		if named, ok := t.Type().(*types.Named); ok {
+			for method := range named.Methods() {
+				start, end := getObjectRange(pkg, method)
+				nodes = append(nodes, graph.Node{
+					Object:          method,
+					Package:         pkg,
+					Kind:            graph.KindFunc,
+					Type:            graph.FuncMethod,
+					Id:              id(method),
+					Parent:          id(t),
+					LocalName:       t.Name() + "." + method.Name(),
+					Pkg:             obj.Pkg().Path(),
+					Position:        sourceRange(pkg, start, end),
+					Test:            isTest,
+					Signature:       funcSignature(method.Name(), method.Signature(), types.RelativeTo(t.Pkg())),
+					ReceiverPointer: hasPointerReceiver(method),
+				})
+			}
+		}
+		return nodes
+	case *types.Const:
+		return []graph.Node{{
+			Object:    obj,
+			Package:   pkg,
+			Kind:      graph.KindConst,
+			Id:        id(t),
+			LocalName: t.Name(),
+			Pkg:       obj.Pkg().Path(),
+			Position:  sourceRange(pkg, start, end),
+			Test:      isTest,
+		}}
+	case *types.Var:
+		return []graph.Node{{
+			Object:    obj,
+			Package:   pkg,
+			Kind:      graph.KindVar,
+			Type:      graph.VarBasic,
+			Id:        id(t),
+			LocalName: t.Name(),
+			Pkg:       obj.Pkg().Path(),
+			Position:  sourceRange(pkg, start, end),
+			Test:      isTest,
+		}}
+	}
+
+	return nil
+}
+
+// markExported sets Exported on all nodes declared by the analyzed packages.
+// Const groups are exported if any of their constants is.
+func (g *builder) markExported() {
+	for _, node := range g.Nodes {
+		// Nodes without objects, e.g. from syntax alone, are already marked
+		if node.Object != nil {
+			node.Exported = g.isExported(node)
+		}
+	}
+	for _, node := range g.Nodes {
+		if parent, ok := g.Nodes[node.Parent]; ok && parent.Type == graph.ConstGroup && node.Exported {
+			parent.Exported = true
+		}
+	}
+}
+
+// markGenerated sets Generated on all nodes declared in generated files
+func (g *builder) markGenerated() {
+	generated := make(map[string]bool)
+	seen := make(map[*packages.Package]bool)
+	for _, node := range g.Nodes {
+		if node.Package == nil || seen[node.Package] {
+			continue
+		}
+		seen[node.Package] = true
+		for _, file := range node.Package.Syntax {
+			if ast.IsGenerated(file) {
+				generated[node.Package.Fset.File(file.Pos()).Name()] = true
+			}
+		}
+	}
+	for _, node := range g.Nodes {
+		if node.Object != nil && node.Package != nil {
+			node.Generated = generated[node.Package.Fset.Position(node.Object.Pos()).Filename]
+		}
+	}
+}
+
+func (g *builder) isExported(node *graph.Node) bool {
+	if node.Object == nil || !node.Object.Exported() {
+		return false
+	}
+	if parent, ok := g.Nodes[node.Parent]; ok && parent.Object != nil {
+		return g.isExported(parent)
+	}
+	return true
+}
+
+// initNodes returns nodes for the declared init functions of pkg, which are
+// not part of the package scope. They are numbered in declaration order
+// like SSA function names (pkg.init#1, pkg.init#2, ...).
+func initNodes(pkg *packages.Package) map[types.Object]*graph.Node {
+	nodes := make(map[types.Object]*graph.Node)
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Name.Name != "init" {
+				continue
+			}
+			obj := pkg.TypesInfo.Defs[fn.Name]
+			if obj == nil {
+				continue
+			}
+			for _, node := range objNodes(pkg, obj) {
+				name := fmt.Sprintf("init#%d", len(nodes)+1)
+				node.Id = obj.Pkg().Path() + "." + name
+				node.LocalName = name
+				nodes[obj] = &node
+			}
+		}
+	}
+	return nodes
+}
+
+// funcSignature formats a function declaration header like
+// "func (s *Server) Serve(l net.Listener) error". Closures have no name.
+func funcSignature(name string, sig *types.Signature, qf types.Qualifier) string {
+	var buf bytes.Buffer
+	buf.WriteString("func")
+	if recv := sig.Recv(); recv != nil {
+		buf.WriteString(" (")
+		if recv.Name() != "" && recv.Name() != "_" {
+			buf.WriteString(recv.Name() + " ")
+		}
+		buf.WriteString(types.TypeString(recv.Type(), qf))
+		buf.WriteString(")")
+	}
+	if name != "" {
+		buf.WriteString(" " + name)
+	}
+	types.WriteSignature(&buf, sig, qf)
+	return buf.String()
+}
+
+// testFuncType classifies a function of a test file by the go test
+// conventions: TestXxx(*testing.T) is a test, BenchmarkXxx(*testing.B) a
+// benchmark, FuzzXxx(*testing.F) a fuzz test and ExampleXxx() an example.
+// Other functions are basic functions.
+func testFuncType(fn *types.Func) string {
+	sig := fn.Signature()
+	if sig.Recv() != nil || sig.Results().Len() != 0 {
+		return graph.FuncBasic
+	}
+	if sig.Params().Len() == 0 && hasTestPrefix(fn.Name(), "Example") {
+		return graph.FuncExample
+	}
+	if sig.Params().Len() != 1 {
+		return graph.FuncBasic
+	}
+	ptr, ok := sig.Params().At(0).Type().(*types.Pointer)
+	if !ok {
+		return graph.FuncBasic
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != "testing" {
+		return graph.FuncBasic
+	}
+	for _, kind := range []struct{ prefix, param, funcType string }{
+		{"Test", "T", graph.FuncTest},
+		{"Benchmark", "B", graph.FuncBenchmark},
+		{"Fuzz", "F", graph.FuncFuzz},
+	} {
+		if named.Obj().Name() == kind.param && hasTestPrefix(fn.Name(), kind.prefix) {
+			return kind.funcType
+		}
+	}
+	return graph.FuncBasic
+}
+
+// hasTestPrefix reports whether name starts with prefix followed by nothing
+// or a character that is not a lower-case letter, like go test requires
+func hasTestPrefix(name, prefix string) bool {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return rest == "" || !unicode.IsLower(r)
+}
+
+// constGroupNodes returns a node for every parenthesized declaration of more
+// than one constant in pkg, e.g. an iota enum, and makes it the parent of the
+// nodes of its constants. Groups are named after the type of their constants
+// if they share one, and after the first constant otherwise.
+func constGroupNodes(pkg *packages.Package, nodes map[string]*graph.Node) []*graph.Node {
+	var groups []*graph.Node
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST || !gen.Lparen.IsValid() {
+				continue
+			}
+
+			var consts []*graph.Node
+			for _, spec := range gen.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					if obj := pkg.TypesInfo.Defs[name]; obj != nil {
+						if node, ok := nodes[id(obj)]; ok {
+							consts = append(consts, node)
+						}
+					}
+				}
+			}
+			if len(consts) < 2 {
+				continue
+			}
+
+			groupName := consts[0].LocalName
+			if named, ok := consts[0].Object.Type().(*types.Named); ok {
+				groupName = named.Obj().Name()
+				for _, node := range consts {
+					if !types.Identical(node.Object.Type(), named) {
+						groupName = consts[0].LocalName
+						break
+					}
+				}
+			}
+
+			group := &graph.Node{
+				Package:   pkg,
+				Kind:      graph.KindConst,
+				Type:      graph.ConstGroup,
+				Id:        pkg.PkgPath + ".const(" + consts[0].LocalName + ")",
+				LocalName: "const(" + groupName + ")",
+				Pkg:       pkg.PkgPath,
+				Position:  sourceRange(pkg, gen.Pos(), gen.End()),
+				Test:      consts[0].Test,
+			}
+			for _, node := range consts {
+				node.Parent = group.Id
+			}
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+func id(obj types.Object) string {
+	pkgPath := ""
+	if obj.Pkg() != nil {
+		pkgPath = obj.Pkg().Path()
+	}
+
+	// Check if the object is a function/method
+	if fn, ok := obj.(*types.Func); ok {
+		sig := fn.Type().(*types.Signature)
+		if recv := sig.Recv(); recv != nil {
+			return fmt.Sprintf("(%s).%s", receiverID(recv.Type()), obj.Name())
+		}
+	}
+
+	// Default for package-level variables, constants, and types
+	if pkgPath == "" {
+		return obj.Name()
+	}
+	return pkgPath + "." + obj.Name()
+}
+
+// hasObjectNode reports whether obj can have a node with the ID id(obj),
+// which holds for package-level declarations and methods. Other objects,
+// e.g. parameters, locals and fields, would resolve to the package-level
+// declarations of the same name.
+func hasObjectNode(obj types.Object) bool {
+	if obj.Pkg() == nil {
+		return true
+	}
+	if fn, ok := obj.(*types.Func); ok && fn.Signature().Recv() != nil {
+		return true
+	}
+	return obj.Parent() == obj.Pkg().Scope()
+}
+
+// receiverID returns the ID of the type a method with receiver type t is
+// declared on. Pointer and value receivers and the type parameters of generic
+// receivers are not distinguished, so methods share the ID prefix of their
+// type and its fields, e.g. (pkg.T).M for func (t *T) M().
+func receiverID(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := types.Unalias(t).(*types.Named); ok {
+		return id(named.Origin().Obj())
+	}
+	return t.String()
+}
+
+// hasPointerReceiver reports whether fn is a method with a pointer receiver
+func hasPointerReceiver(fn *types.Func) bool {
+	recv := fn.Signature().Recv()
+	if recv == nil {
+		return false
+	}
+	_, ok := recv.Type().(*types.Pointer)
+	return ok
+}
+
+// promotionPath returns the IDs of the field nodes a selection goes through:
+// the embedded fields a promoted field or method is promoted from, followed
+// by the promoted field itself. Direct selections yield no IDs.
+func promotionPath(sel *types.Selection) []string {
+	index := sel.Index()
+	if len(index) < 2 {
+		return nil
+	}
+
+	var ids []string
+	t := sel.Recv()
+	for i, idx := range index {
+		// The last index of a method selection refers to the method set
+		if i == len(index)-1 && sel.Kind() != types.FieldVal {
+			break
+		}
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		named, ok := types.Unalias(t).(*types.Named)
+		if !ok {
+			break
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok || idx >= st.NumFields() {
+			break
+		}
+		field := st.Field(idx)
+		ids = append(ids, "("+id(named.Obj())+")."+field.Name())
+		t = field.Type()
+	}
+	return ids
+}
+
+func underlyingTypes(t types.Type) []types.Type {
+	switch t := t.(type) {
+	case *types.Pointer:
+		return underlyingTypes(t.Elem())
+	case *types.Map:
+		return append(underlyingTypes(t.Key()), underlyingTypes(t.Elem())...)
+	case *types.Array:
+		return underlyingTypes(t.Elem())
+	case *types.Slice:
+		return underlyingTypes(t.Elem())
+	case *types.Chan:
+		return underlyingTypes(t.Elem())
+	}
+
+	return []types.Type{t}
+}
+
+func getObjectRange(pkg *packages.Package, obj types.Object) (start, end token.Pos) {
+	pos := obj.Pos()
+	if pos == token.NoPos {
+		return pos, pos
+	}
+
+	var targetFile *ast.File
+	for _, f := range pkg.Syntax {
+		if pos >= f.Pos() && pos <= f.End() {
+			targetFile = f
+			break
+		}
+	}
+	if targetFile == nil {
+		return pos, pos
+	}
+	path, _ := astutil.PathEnclosingInterval(targetFile, pos, pos)
+
+	for _, node := range path {
+		switch n := node.(type) {
+		case *ast.ValueSpec:
+			// Each of several names spans from the name to its initializer
+			if len(n.Names) > 1 && len(n.Values) == len(n.Names) {
+				for i, name := range n.Names {
+					if name.Pos() == pos {
+						return name.Pos(), n.Values[i].End()
+					}
+				}
+			}
+			return n.Pos(), n.End()
+		case *ast.Field, *ast.TypeSpec, *ast.FuncDecl:
+			return n.Pos(), n.End()
+		}
+	}
+	return pos, pos
+}
+
+func sourceRange(pkg *packages.Package, start token.Pos, end token.Pos) *graph.Position {
+	startPos := pkg.Fset.Position(start)
+	endPos := pkg.Fset.Position(end)
+
+	filename := startPos.Filename
+
+	if pkg.Module != nil {
+		if rel, err := filepath.Rel(pkg.Module.Dir, filename); err == nil {
+			filename = rel
+		}
+	}
+
+	return &graph.Position{
+		File:      filename,
+		StartLine: startPos.Line,
+		StartCol:  startPos.Column,
+		EndLine:   endPos.Line,
+		EndCol:    endPos.Column,
+	}
+}
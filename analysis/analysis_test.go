@@ -0,0 +1,233 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// fixture is a package example.com/fx whose tests are added by the test
+// cases that need them
+const fixture = `package fx
+
+func compute() int { return 1 }
+
+func Run() int {
+	f := func() int {
+		return compute() + compute()
+	}
+	return f() + compute()
+}
+
+type T struct{ n int }
+
+func (t T) M() int { return t.n }
+
+func Call(t T) int { return t.M() + t.n }
+`
+
+const fixtureTest = `package fx
+
+import "testing"
+
+func TestRun(t *testing.T) { Run() }
+`
+
+// analyzeFixture writes files into a module example.com/fx and analyzes all
+// of its packages
+func analyzeFixture(t *testing.T, opts Options, files map[string]string) *graph.Graph {
+	t.Helper()
+	dir := t.TempDir()
+	files = maps.Clone(files)
+	files["go.mod"] = "module example.com/fx\n\ngo 1.22\n"
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts.Dir = dir
+	// Type-check dependencies from source, so the tests do not depend on the
+	// export data format of the installed toolchain
+	opts.Load = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+		cfg.Mode |= packages.NeedDeps
+		return packages.Load(cfg, patterns...)
+	}
+	g, err := Analyze(&opts, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// linkWeight returns the weight of the link from one node to another, or 0
+// if there is none
+func linkWeight(g *graph.Graph, from, to, kind string) int {
+	for _, link := range g.Links {
+		if link.From == from && link.To == to && link.Kind == kind {
+			return link.Weight
+		}
+	}
+	return 0
+}
+
+func TestLinkWeights(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"without tests", map[string]string{"fx.go": fixture}},
+		// The test variant of the package shares its parsed files
+		{"with tests", map[string]string{"fx.go": fixture, "fx_test.go": fixtureTest}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := analyzeFixture(t, Options{Closures: true}, tt.files)
+			links := []struct {
+				from, to, kind string
+				weight         int
+			}{
+				{"example.com/fx.Run$1", "example.com/fx.compute", graph.LinkReference, 2},
+				{"example.com/fx.Run", "example.com/fx.compute", graph.LinkReference, 1},
+				{"example.com/fx.Run$1", "example.com/fx.Run", graph.LinkParent, 1},
+				// Method calls and field accesses are linked once
+				{"example.com/fx.Call", "(example.com/fx.T).M", graph.LinkReference, 1},
+				{"example.com/fx.Call", "(example.com/fx.T).n", graph.LinkReference, 1},
+				{"(example.com/fx.T).M", "(example.com/fx.T).n", graph.LinkReference, 1},
+			}
+			for _, l := range links {
+				if got := linkWeight(g, l.from, l.to, l.kind); got != l.weight {
+					t.Errorf("weight of %s link %s -> %s = %d, want %d", l.kind, l.from, l.to, got, l.weight)
+				}
+			}
+		})
+	}
+}
+
+func TestCallWeights(t *testing.T) {
+	// The SSA program has functions for the package and its test variant
+	g := analyzeFixture(t, Options{Closures: true, CallGraph: CallGraphCHA}, map[string]string{"fx.go": fixture, "fx_test.go": fixtureTest})
+	if got := linkWeight(g, "example.com/fx.Run$1", "example.com/fx.compute", graph.LinkCall); got != 2 {
+		t.Errorf("weight of call link Run$1 -> compute = %d, want 2", got)
+	}
+	if got := linkWeight(g, "example.com/fx.Run", "example.com/fx.compute", graph.LinkCall); got != 1 {
+		t.Errorf("weight of call link Run -> compute = %d, want 1", got)
+	}
+}
+
+func TestClosureNodes(t *testing.T) {
+	const nested = `package fx
+
+func Serve() {
+	go func() {
+		defer func() {
+			func() {}()
+		}()
+	}()
+}
+`
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"without tests", map[string]string{"fx.go": nested}},
+		{"with tests", map[string]string{"fx.go": nested, "fx_test.go": "package fx\n\nimport \"testing\"\n\nfunc TestServe(t *testing.T) { Serve() }\n"}},
+	}
+	want := map[string]string{
+		"example.com/fx.Serve$1":     "example.com/fx.Serve",
+		"example.com/fx.Serve$1$1":   "example.com/fx.Serve$1",
+		"example.com/fx.Serve$1$1$1": "example.com/fx.Serve$1$1",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := analyzeFixture(t, Options{Closures: true}, tt.files)
+			closures := 0
+			for _, node := range g.Nodes {
+				if node.Type != graph.FuncClosure {
+					continue
+				}
+				closures++
+				if parent, ok := want[node.Id]; !ok || node.Parent != parent {
+					t.Errorf("closure %s with parent %s, want %q", node.Id, node.Parent, parent)
+				}
+				if got := linkWeight(g, node.Id, node.Parent, graph.LinkParent); got != 1 {
+					t.Errorf("weight of parent link of %s = %d, want 1", node.Id, got)
+				}
+			}
+			if closures != len(want) {
+				t.Errorf("got %d closure nodes, want %d", closures, len(want))
+			}
+		})
+	}
+}
+
+func TestLocalObjects(t *testing.T) {
+	// Parameters, receivers, locals and fields named like package-level
+	// declarations must not be linked to them
+	const shadowing = `package fx
+
+var Count, b, N int
+
+type Base struct{ N int }
+
+func Run(Count int) int {
+	N := Count
+	return N
+}
+
+func (b Base) Get() int { return b.N }
+`
+	g := analyzeFixture(t, Options{}, map[string]string{"fx.go": shadowing})
+	for _, link := range g.Links {
+		switch link.From {
+		case "example.com/fx.Count", "example.com/fx.b", "example.com/fx.N":
+			t.Errorf("unexpected link %s -> %s", link.From, link.To)
+		}
+		switch link.To {
+		case "example.com/fx.Count", "example.com/fx.b", "example.com/fx.N":
+			t.Errorf("unexpected link %s -> %s", link.From, link.To)
+		}
+	}
+	if got := linkWeight(g, "(example.com/fx.Base).Get", "example.com/fx.Base", graph.LinkReference); got != 1 {
+		t.Errorf("weight of receiver type link Get -> Base = %d, want 1", got)
+	}
+}
+
+func TestGoDeferLinks(t *testing.T) {
+	const goDefer = `package fx
+
+type T struct{}
+
+func (T) M()     {}
+func (T) Close() {}
+
+func Use(t T) {
+	go t.M()
+	defer t.Close()
+}
+`
+	g := analyzeFixture(t, Options{}, map[string]string{"fx.go": goDefer})
+	var got []string
+	for _, link := range g.Links {
+		if link.From == "example.com/fx.Use" {
+			got = append(got, fmt.Sprintf("%s %s x%d", link.To, link.Kind, link.Weight))
+		}
+	}
+	slices.Sort(got)
+	// Methods called in go and defer statements have no reference link
+	want := []string{
+		"(example.com/fx.T).Close defer x1",
+		"(example.com/fx.T).M go x1",
+		"example.com/fx.T reference x1",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("links of Use = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// Call graph algorithms selectable with -callgraph
+const (
+	CallGraphNone = ""
+	CallGraphCHA  = "cha"
+	CallGraphRTA  = "rta"
+	CallGraphVTA  = "vta"
+	CallGraphPTA  = "pta"
+)
+
+// CallGraphModes lists the call graph algorithms
+var CallGraphModes = []string{CallGraphCHA, CallGraphRTA, CallGraphVTA, CallGraphPTA}
+
+// maxPTARounds bounds the number of VTA refinement rounds in pta mode
+const maxPTARounds = 5
+
+// buildCallGraph builds the SSA form of the loaded packages and computes a
+// call graph with the given algorithm. Unlike the AST reference walk, this
+// resolves dynamic calls through interfaces and function values.
+func buildCallGraph(mode string, pkgs []*packages.Package) (*callgraph.Graph, error) {
+	prog, ssaPkgs := ssautil.Packages(pkgs, ssa.InstantiateGenerics)
+	prog.Build()
+
+	switch mode {
+	case CallGraphCHA:
+		return cha.CallGraph(prog), nil
+	case CallGraphRTA:
+		var roots []*ssa.Function
+		for _, pkg := range ssaPkgs {
+			if pkg == nil {
+				continue
+			}
+			// Every function of the analyzed packages is a potential entry
+			// point, since libraries have no main function.
+			for _, member := range pkg.Members {
+				if fn, ok := member.(*ssa.Function); ok {
+					roots = append(roots, fn)
+				}
+			}
+		}
+		return rta.Analyze(roots, true).CallGraph, nil
+	case CallGraphVTA:
+		return vta.CallGraph(ssautil.AllFunctions(prog), cha.CallGraph(prog)), nil
+	case CallGraphPTA:
+		return refinedCallGraph(prog), nil
+	}
+
+	return nil, fmt.Errorf("unknown call graph algorithm %q", mode)
+}
+
+// refinedCallGraph resolves interface method calls and function values to
+// concrete callees by repeatedly running VTA, each round seeded with the
+// previous result, until the graph no longer shrinks. The go/pointer
+// analysis this mode used to be modelled on is no longer part of x/tools;
+// iterated VTA is its recommended replacement. Each round costs as much as
+// a full vta run.
+func refinedCallGraph(prog *ssa.Program) *callgraph.Graph {
+	funcs := ssautil.AllFunctions(prog)
+	cg := cha.CallGraph(prog)
+	edges := countEdges(cg)
+	for range maxPTARounds {
+		refined := vta.CallGraph(funcs, cg)
+		n := countEdges(refined)
+		cg = refined
+		if n >= edges {
+			break
+		}
+		edges = n
+	}
+	return cg
+}
+
+func countEdges(cg *callgraph.Graph) int {
+	n := 0
+	callgraph.GraphVisitEdges(cg, func(*callgraph.Edge) error {
+		n++
+		return nil
+	})
+	return n
+}
+
+// ssaNodeID returns the graph node ID of fn, or an empty string for synthetic
+// functions that have no declaration. Wrappers and thunks map to the method
+// they wrap. Anonymous functions map to their closure node if there is one
+// and to their enclosing declaration otherwise.
+func (g *builder) ssaNodeID(fn *ssa.Function) string {
+	top := fn
+	for top.Parent() != nil {
+		top = top.Parent()
+	}
+	if origin := top.Origin(); origin != nil {
+		top = origin
+	}
+	if top.Object() == nil {
+		return ""
+	}
+
+	topID := g.objID(top.Object())
+	if fn != top {
+		closureID := topID + strings.TrimPrefix(fn.Name(), top.Name())
+		if _, ok := g.Nodes[closureID]; ok {
+			return closureID
+		}
+	}
+	return topID
+}
+
+// addCallEdges inserts a call link for every edge of cg whose caller and
+// callee are both nodes of the graph. Test variants of a package have their
+// own SSA functions for the same source, so every call site is counted once.
+func (g *builder) addCallEdges(cg *callgraph.Graph, links graph.LinkSet) {
+	type callSite struct {
+		from, to string
+		pos      token.Pos
+	}
+	seen := make(map[callSite]bool)
+	callgraph.GraphVisitEdges(cg, func(edge *callgraph.Edge) error {
+		from := g.ssaNodeID(edge.Caller.Func)
+		to := g.ssaNodeID(edge.Callee.Func)
+		if from == "" || to == "" || from == to {
+			return nil
+		}
+		if _, ok := g.Nodes[from]; !ok {
+			return nil
+		}
+		if _, ok := g.Nodes[to]; !ok {
+			return nil
+		}
+		site := callSite{from, to, edge.Pos()}
+		if seen[site] {
+			return nil
+		}
+		seen[site] = true
+		links.Insert(from, to, graph.LinkCall)
+		return nil
+	})
+}
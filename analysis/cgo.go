@@ -0,0 +1,95 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/ast"
+	"go/types"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// cgoPkg is the ID and package path of the synthetic node grouping the C
+// symbols referenced through cgo
+const cgoPkg = "C"
+
+// cgoPrefixes maps the prefixes cgo gives the Go declarations generated for
+// C symbols to the kind of node representing the symbol
+var cgoPrefixes = []struct {
+	prefix, kind string
+}{
+	{"_Cfunc_", graph.KindFunc},
+	{"_Cmacro_", graph.KindFunc},
+	{"_Ctype_", graph.KindType},
+	{"_Cvar_", graph.KindVar},
+	{"_Ciconst_", graph.KindConst},
+	{"_Cfconst_", graph.KindConst},
+	{"_Csconst_", graph.KindConst},
+}
+
+// isCgoGenerated reports whether obj is declared in a file generated by cgo
+// preprocessing rather than in one of the package's source files. Positions
+// in the rewritten source files are mapped back to the originals by line
+// directives.
+func isCgoGenerated(pkg *packages.Package, obj types.Object) bool {
+	if len(pkg.CompiledGoFiles) == 0 || slices.Equal(pkg.CompiledGoFiles, pkg.GoFiles) {
+		return false
+	}
+	return !slices.Contains(pkg.GoFiles, pkg.Fset.Position(obj.Pos()).Filename)
+}
+
+// cgoObjectNode returns the node for the C symbol behind a declaration
+// generated by cgo, e.g. C.puts for _Cfunc_puts, or nil if obj is a cgo
+// helper that does not correspond to a C symbol.
+func (g *builder) cgoObjectNode(obj types.Object) *graph.Node {
+	for _, p := range cgoPrefixes {
+		if name, ok := strings.CutPrefix(obj.Name(), p.prefix); ok {
+			return g.cgoNode(name, p.kind)
+		}
+	}
+	return nil
+}
+
+// cgoSelectorNode returns the node for a C.name selector in source that was
+// not preprocessed by cgo, e.g. because no C compiler is available. Only the
+// syntax is known in that case, so calls are represented as functions and
+// everything else as variables.
+func (g *builder) cgoSelectorNode(pkg *packages.Package, e *ast.SelectorExpr, callees map[*ast.Ident]bool) *graph.Node {
+	x, ok := e.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	pkgName, ok := pkg.TypesInfo.Uses[x].(*types.PkgName)
+	if !ok || pkgName.Imported().Path() != cgoPkg {
+		return nil
+	}
+	if callees[e.Sel] {
+		return g.cgoNode(e.Sel.Name, graph.KindFunc)
+	}
+	return g.cgoNode(e.Sel.Name, graph.KindVar)
+}
+
+// cgoNode returns the node for the C symbol name, creating it and the
+// synthetic C package node if needed.
+func (g *builder) cgoNode(name, kind string) *graph.Node {
+	g.addNode(&graph.Node{
+		Kind:      graph.KindPackage,
+		Id:        cgoPkg,
+		LocalName: cgoPkg,
+		Pkg:       cgoPkg,
+		External:  true,
+	})
+
+	nodeID := cgoPkg + "." + name
+	return g.addNode(&graph.Node{
+		Kind:      kind,
+		Id:        nodeID,
+		Parent:    cgoPkg,
+		LocalName: nodeID,
+		Pkg:       cgoPkg,
+		External:  true,
+	})
+}
@@ -0,0 +1,152 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/internal/git"
+	"golang.org/x/tools/go/packages"
+)
+
+// analyzeChanged analyzes the packages matching paths that changed since the
+// git revision opts.ChangedSince and the packages depending on them, directly
+// or transitively. A package changed if a file in its directory was added,
+// modified or removed, including uncommitted and untracked files, or if the
+// go.mod or go.sum file of its module changed. Declarations of the other
+// packages are leaf nodes.
+func analyzeChanged(opts *Options, paths ...string) (*graph.Graph, error) {
+	if err := unsupported("ChangedSince", opts.wholeGraphOptions()); err != nil {
+		return nil, err
+	}
+	dir := opts.Dir
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	files, err := changedFiles(dir, opts.ChangedSince)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Workspace {
+		patterns, err := workspacePatterns(opts.Dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, patterns...)
+	}
+
+	load := packages.Load
+	if opts.Load != nil {
+		load = opts.Load
+	}
+	cfg := opts.packagesConfig()
+	cfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedModule
+	pkgs, err := load(cfg, paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test variants of a package have IDs of the form "p [p.test]" and
+	// import the test variants of other packages
+	ids := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		ids[pkg.ID] = true
+	}
+	all := make(map[string]bool, len(pkgs))
+	importedBy := make(map[string][]string)
+	var affected []string
+	for _, pkg := range pkgs {
+		all[pkg.PkgPath] = true
+		for _, imp := range pkg.Imports {
+			importedBy[imp.ID] = append(importedBy[imp.ID], pkg.ID)
+		}
+		if changedPackage(pkg, files) {
+			affected = append(affected, pkg.ID)
+		}
+	}
+	seen := make(map[string]bool)
+	for len(affected) > 0 {
+		id := affected[len(affected)-1]
+		affected = affected[:len(affected)-1]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		affected = append(affected, importedBy[id]...)
+	}
+
+	var changed []string
+	for id := range seen {
+		path, _, isVariant := strings.Cut(id, " [")
+		if isVariant {
+			// The variant belongs to the package under test
+			path = strings.TrimSuffix(strings.TrimSuffix(id[len(path)+2:], "]"), ".test")
+		} else if testMain, ok := strings.CutSuffix(id, ".test"); ok && ids[testMain] {
+			// The generated main package of a test binary, whose package
+			// under test is affected too
+			continue
+		}
+		changed = append(changed, path)
+	}
+	slices.Sort(changed)
+	changed = slices.Compact(changed)
+
+	changedOpts := *opts
+	changedOpts.ChangedSince = ""
+	changedOpts.Workspace = false
+	changedOpts.leafPkgs = all
+	if len(changed) == 0 {
+		return &graph.Graph{Nodes: make(map[string]*graph.Node), PositionStrings: opts.PositionStrings}, nil
+	}
+	return Analyze(&changedOpts, changed...)
+}
+
+// changedFiles returns the absolute paths of the files of the repository
+// containing dir that differ from the git revision rev, including untracked
+// files that are not ignored
+func changedFiles(dir, rev string) (map[string]bool, error) {
+	top, err := git.Run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	diff, err := git.Run(top, "diff", "--name-only", "--no-renames", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git.Run(top, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	for _, out := range []string{diff, untracked} {
+		for file := range strings.Lines(out) {
+			if file = strings.TrimSpace(file); file != "" {
+				files[filepath.Join(top, filepath.FromSlash(file))] = true
+			}
+		}
+	}
+	return files, nil
+}
+
+// changedPackage reports whether one of the files is in the directory of pkg
+// or is the go.mod or go.sum file of its module
+func changedPackage(pkg *packages.Package, files map[string]bool) bool {
+	if pkg.Module != nil && pkg.Module.GoMod != "" {
+		if files[pkg.Module.GoMod] || files[strings.TrimSuffix(pkg.Module.GoMod, ".mod")+".sum"] {
+			return true
+		}
+	}
+	for file := range files {
+		if filepath.Dir(file) == pkg.Dir {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+)
+
+// Classifier returns the group of a node, or "" if it does not classify the
+// node
+type Classifier func(node *graph.Node) string
+
+// ClassifyRule assigns a group to the nodes matching all of its conditions.
+// Conditions that are not set match every node.
+type ClassifyRule struct {
+	Group string `yaml:"group"`
+	// Package is a package path, optionally ending in /... to include all
+	// packages below it
+	Package string `yaml:"package"`
+	// Path is a part of the package path, e.g. /internal/mock to match all
+	// packages below any internal/mock directory
+	Path string `yaml:"path"`
+	// Prefix and Suffix match the start and end of the name of the node,
+	// without its package and receiver type
+	Prefix string `yaml:"prefix"`
+	Suffix string `yaml:"suffix"`
+	// Kind and Type match the kind and type of the node, e.g. func and
+	// method
+	Kind string `yaml:"kind"`
+	Type string `yaml:"type"`
+}
+
+// Classifier returns the classifier assigning the group of r
+func (r ClassifyRule) Classifier() (Classifier, error) {
+	if r.Group == "" {
+		return nil, fmt.Errorf("rule has no group")
+	}
+	return func(node *graph.Node) string {
+		name := graph.MethodName(node)
+		switch {
+		case r.Package != "" && !matchPackagePattern(r.Package, node.Pkg),
+			r.Path != "" && !strings.Contains(node.Pkg+"/", strings.TrimSuffix(r.Path, "/")+"/"),
+			!strings.HasPrefix(name, r.Prefix),
+			!strings.HasSuffix(name, r.Suffix),
+			r.Kind != "" && node.Kind != r.Kind,
+			r.Type != "" && node.Type != r.Type:
+			return ""
+		}
+		return r.Group
+	}, nil
+}
+
+// classify sets the group of every node to the group of the first of
+// classifiers returning one
+func (g *builder) classify(classifiers []Classifier) {
+	for _, node := range g.Nodes {
+		for _, classifier := range classifiers {
+			if group := classifier(node); group != "" {
+				node.Group = group
+				break
+			}
+		}
+	}
+}
+
+// matchPackagePattern reports whether pkg matches pattern, which is a
+// package path optionally ending in /... to include all packages below it
+func matchPackagePattern(pattern, pkg string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+	return pkg == pattern
+}
@@ -0,0 +1,41 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"unique"
+)
+
+// compact drops the references of the nodes to the objects and packages
+// they were built from, which keep the syntax and type information of all
+// loaded packages reachable, along with the indexes of the builder. Strings
+// built for every node, e.g. file names relative to the module, are
+// replaced by a shared copy.
+func (g *builder) compact() {
+	for _, node := range g.Nodes {
+		node.Object = nil
+		node.Package = nil
+		node.Pkg = intern(node.Pkg)
+		node.Module = intern(node.Module)
+		node.Parent = intern(node.Parent)
+		if node.Position != nil {
+			node.Position.File = intern(node.Position.File)
+		}
+		for i, platform := range node.Platforms {
+			node.Platforms[i] = intern(platform)
+		}
+	}
+	for i, link := range g.Links {
+		g.Links[i].From = intern(link.From)
+		g.Links[i].To = intern(link.To)
+	}
+	g.closures = nil
+	g.inits = nil
+	g.decls = nil
+}
+
+// intern returns a copy of s sharing its memory with all other interned
+// copies of s
+func intern(s string) string {
+	return unique.Make(s).Value()
+}
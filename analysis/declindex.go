@@ -0,0 +1,85 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/ast"
+	"go/token"
+	"iter"
+	"sort"
+)
+
+// declIndex indexes the syntax of a file that nodes can be attributed to by
+// its source interval: function declarations and literals, fields and type
+// and value specs. Finding the declarations enclosing a syntax node is a
+// binary search instead of a walk of the file.
+type declIndex struct {
+	// decls are sorted by position, enclosing declarations before the
+	// declarations they enclose
+	decls []ast.Node
+	// parents holds the index of the innermost declaration enclosing each
+	// of decls, or -1
+	parents []int
+}
+
+func newDeclIndex(file *ast.File) *declIndex {
+	idx := &declIndex{}
+	// enclosing holds the innermost declaration of every node on the path
+	// to the current node
+	var enclosing []int
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			enclosing = enclosing[:len(enclosing)-1]
+			return false
+		}
+		parent := -1
+		if len(enclosing) > 0 {
+			parent = enclosing[len(enclosing)-1]
+		}
+		switch n.(type) {
+		case *ast.FuncLit, *ast.FuncDecl, *ast.Field, *ast.TypeSpec, *ast.ValueSpec:
+			idx.decls = append(idx.decls, n)
+			idx.parents = append(idx.parents, parent)
+			parent = len(idx.decls) - 1
+		}
+		enclosing = append(enclosing, parent)
+		return true
+	})
+	return idx
+}
+
+// enclosing yields the declarations enclosing the interval from pos to end,
+// innermost first
+func (idx *declIndex) enclosing(pos, end token.Pos) iter.Seq[ast.Node] {
+	return func(yield func(ast.Node) bool) {
+		// Declarations nest, so the enclosing ones are the last declaration
+		// starting before pos and the declarations enclosing it
+		i := sort.Search(len(idx.decls), func(i int) bool { return idx.decls[i].Pos() > pos }) - 1
+		for ; i >= 0; i = idx.parents[i] {
+			if decl := idx.decls[i]; end <= decl.End() {
+				if !yield(decl) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// declIndex returns the declaration index of file, building it on first use
+func (g *builder) declIndex(file *ast.File) *declIndex {
+	g.mu.RLock()
+	idx := g.decls[file]
+	g.mu.RUnlock()
+	if idx != nil {
+		return idx
+	}
+
+	idx = newDeclIndex(file)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if existing, ok := g.decls[file]; ok {
+		return existing
+	}
+	g.decls[file] = idx
+	return idx
+}
@@ -0,0 +1,287 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Import paths of the supported dependency injection frameworks
+const (
+	wirePkg = "github.com/google/wire"
+	fxPkg   = "go.uber.org/fx"
+	digPkg  = "go.uber.org/dig"
+)
+
+var (
+	errorType   = types.Universe.Lookup("error").Type()
+	cleanupType = types.NewSignatureType(nil, nil, nil, nil, nil, false)
+)
+
+// diProvider is a node registered with a dependency injection container,
+// e.g. a constructor passed to wire.NewSet or fx.Provide
+type diProvider struct {
+	node     string
+	provides []types.Type
+	consumes []types.Type
+}
+
+// diContainer collects the providers and consumers registered with google/wire
+// and uber/fx across all analyzed packages
+type diContainer struct {
+	providers []diProvider
+	// consumers are the nodes that only request values, e.g. functions
+	// passed to fx.Invoke and wire injectors
+	consumers []diProvider
+	// bindings maps interfaces to the concrete types bound to them with
+	// wire.Bind
+	bindings typeutil.Map
+}
+
+// collectDI records the providers, consumers and interface bindings
+// registered in pkg. Providers and consumers must be nodes of the graph.
+func (g *builder) collectDI(pkg *packages.Package, di *diContainer) {
+	if !importsDI(pkg) {
+		return
+	}
+	for _, file := range g.syntax(pkg) {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			switch diCallee(pkg, call) {
+			case wirePkg + ".NewSet", fxPkg + ".Provide":
+				for _, arg := range call.Args {
+					g.addDIProvider(pkg, file, arg, di)
+				}
+			case wirePkg + ".Build":
+				for _, arg := range call.Args {
+					g.addDIProvider(pkg, file, arg, di)
+				}
+				// The injector function calling wire.Build requests its
+				// results from the container
+				for _, node := range g.findContainingNodes(pkg, file, call) {
+					fn, ok := node.Object.(*types.Func)
+					if !ok {
+						continue
+					}
+					provides, _ := diSignature(fn.Signature())
+					di.consumers = append(di.consumers, diProvider{node: node.Id, consumes: provides})
+				}
+			case fxPkg + ".Invoke":
+				for _, arg := range call.Args {
+					if fn := diFunc(pkg, arg); fn != nil {
+						if _, ok := g.Nodes[id(fn)]; ok {
+							_, consumes := diSignature(fn.Signature())
+							di.consumers = append(di.consumers, diProvider{node: id(fn), consumes: consumes})
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// addDIProvider records the provider expression arg of a wire provider set
+// or fx.Provide call
+func (g *builder) addDIProvider(pkg *packages.Package, file *ast.File, arg ast.Expr, di *diContainer) {
+	arg = ast.Unparen(arg)
+	if fn := diFunc(pkg, arg); fn != nil {
+		if _, ok := g.Nodes[id(fn)]; ok {
+			provides, consumes := diSignature(fn.Signature())
+			di.providers = append(di.providers, diProvider{id(fn), provides, consumes})
+		}
+		return
+	}
+	call, ok := arg.(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	switch diCallee(pkg, call) {
+	case fxPkg + ".Annotate":
+		if len(call.Args) > 0 {
+			g.addDIProvider(pkg, file, call.Args[0], di)
+		}
+	case wirePkg + ".Struct":
+		// wire.Struct(new(T), "*") provides T and *T from the fields of T
+		if len(call.Args) == 0 {
+			return
+		}
+		ptr, ok := pkg.TypesInfo.TypeOf(call.Args[0]).(*types.Pointer)
+		if !ok {
+			return
+		}
+		named, ok := types.Unalias(ptr.Elem()).(*types.Named)
+		if !ok {
+			return
+		}
+		if _, ok := g.Nodes[id(named.Obj())]; !ok {
+			return
+		}
+		provider := diProvider{node: id(named.Obj()), provides: []types.Type{named, ptr}}
+		if st, ok := named.Underlying().(*types.Struct); ok {
+			for field := range st.Fields() {
+				provider.consumes = append(provider.consumes, field.Type())
+			}
+		}
+		di.providers = append(di.providers, provider)
+	case wirePkg + ".Bind":
+		// wire.Bind(new(I), new(T)) satisfies requests for I with T
+		if len(call.Args) != 2 {
+			return
+		}
+		iface, ok1 := pkg.TypesInfo.TypeOf(call.Args[0]).(*types.Pointer)
+		impl, ok2 := pkg.TypesInfo.TypeOf(call.Args[1]).(*types.Pointer)
+		if ok1 && ok2 {
+			di.bindings.Set(iface.Elem(), impl.Elem())
+		}
+	case wirePkg + ".Value", wirePkg + ".InterfaceValue", fxPkg + ".Supply":
+		// Values are provided by the declaration building the set, e.g. the
+		// variable holding it
+		var provides []types.Type
+		for _, value := range call.Args {
+			if typ := pkg.TypesInfo.TypeOf(value); typ != nil {
+				provides = append(provides, typ)
+			}
+		}
+		if diCallee(pkg, call) == wirePkg+".InterfaceValue" && len(provides) == 2 {
+			ptr, ok := provides[0].(*types.Pointer)
+			if !ok {
+				return
+			}
+			provides = []types.Type{ptr.Elem()}
+		}
+		for _, node := range g.findContainingNodes(pkg, file, call) {
+			di.providers = append(di.providers, diProvider{node: node.Id, provides: provides})
+		}
+	}
+}
+
+// addDIEdges links every provider to the types it provides and every
+// provider and consumer to the providers of the values it requests. All
+// registrations are treated as one container, so the links show which
+// providers could satisfy a request, not which one a particular injector or
+// fx application uses.
+func (g *builder) addDIEdges(di *diContainer, links graph.LinkSet) {
+	var providersOf typeutil.Map
+	for _, provider := range di.providers {
+		for _, typ := range provider.provides {
+			ids, _ := providersOf.At(typ).([]string)
+			providersOf.Set(typ, append(ids, provider.node))
+			for _, t := range underlyingTypes(typ) {
+				if typeNode, ok := g.Nodes[t.String()]; ok && typeNode.Id != provider.node {
+					links.Insert(provider.node, typeNode.Id, graph.LinkProvides)
+				}
+			}
+		}
+	}
+	for _, consumer := range append(di.providers, di.consumers...) {
+		for _, typ := range consumer.consumes {
+			ids, _ := providersOf.At(typ).([]string)
+			if impl := di.bindings.At(typ); impl != nil {
+				implIDs, _ := providersOf.At(impl.(types.Type)).([]string)
+				ids = append(ids, implIDs...)
+			}
+			for _, providerID := range ids {
+				if providerID != consumer.node {
+					links.Insert(consumer.node, providerID, graph.LinkConsumes)
+				}
+			}
+		}
+	}
+}
+
+// importsDI reports whether pkg imports one of the supported dependency
+// injection frameworks
+func importsDI(pkg *packages.Package) bool {
+	for path := range pkg.Imports {
+		if path == wirePkg || path == fxPkg {
+			return true
+		}
+	}
+	return false
+}
+
+// diCallee returns the qualified name of the dependency injection framework
+// function called by call, e.g. "go.uber.org/fx.Provide", or "" if call does
+// not call one
+func diCallee(pkg *packages.Package, call *ast.CallExpr) string {
+	fn, ok := typeutil.Callee(pkg.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return ""
+	}
+	if path := fn.Pkg().Path(); path != wirePkg && path != fxPkg {
+		return ""
+	}
+	return fn.Pkg().Path() + "." + fn.Name()
+}
+
+// diFunc returns the function or method that expr refers to, or nil if expr
+// is not a function value
+func diFunc(pkg *packages.Package, expr ast.Expr) *types.Func {
+	var ident *ast.Ident
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	default:
+		return nil
+	}
+	fn, ok := pkg.TypesInfo.Uses[ident].(*types.Func)
+	if !ok {
+		return nil
+	}
+	return fn.Origin()
+}
+
+// diSignature returns the types provided by a constructor with signature
+// sig and the types it requests. Errors and cleanup functions are not
+// provided. Parameter structs embedding fx.In request their fields, result
+// structs embedding fx.Out provide their fields.
+func diSignature(sig *types.Signature) (provides, consumes []types.Type) {
+	for result := range sig.Results().Variables() {
+		typ := result.Type()
+		if types.Identical(typ, errorType) || types.Identical(typ, cleanupType) {
+			continue
+		}
+		provides = append(provides, diFields(typ, "Out")...)
+	}
+	for param := range sig.Params().Variables() {
+		consumes = append(consumes, diFields(param.Type(), "In")...)
+	}
+	return provides, consumes
+}
+
+// diFields returns the types of the fields of typ if it is a parameter
+// object, i.e. a struct embedding fx.In or fx.Out as given by marker, or
+// typ itself otherwise
+func diFields(typ types.Type, marker string) []types.Type {
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return []types.Type{typ}
+	}
+	var fields []types.Type
+	isParamObject := false
+	for field := range st.Fields() {
+		if named, ok := types.Unalias(field.Type()).(*types.Named); ok && field.Embedded() && named.Obj().Name() == marker && named.Obj().Pkg() != nil {
+			if path := named.Obj().Pkg().Path(); path == fxPkg || path == digPkg || strings.HasPrefix(path, digPkg+"/") {
+				isParamObject = true
+				continue
+			}
+		}
+		fields = append(fields, field.Type())
+	}
+	if !isParamObject {
+		return []types.Type{typ}
+	}
+	return fields
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/ast"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// Compiler and tool directives recorded on the declarations they belong to
+var recordedDirectives = []string{"go:generate", "go:linkname", "go:embed"}
+
+// addDirectives records the //go:generate, //go:linkname and //go:embed
+// directives of file in the directives of the declarations they belong to.
+// Directives belong to the declaration they document, //go:linkname to the
+// local symbol it names, and //go:generate directives outside of a
+// declaration comment to the package. //go:linkname also links the local
+// symbol to the symbol it is linked to if that is part of the graph.
+func (g *builder) addDirectives(pkg *packages.Package, file *ast.File, links graph.LinkSet) {
+	documents := make(map[*ast.CommentGroup][]*graph.Node)
+	addDoc := func(doc *ast.CommentGroup, names ...*ast.Ident) {
+		if doc == nil {
+			return
+		}
+		for _, name := range names {
+			obj := pkg.TypesInfo.Defs[name]
+			if obj == nil {
+				continue
+			}
+			if node, ok := g.Nodes[g.objID(obj)]; ok {
+				documents[doc] = append(documents[doc], node)
+			}
+		}
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			addDoc(decl.Doc, decl.Name)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					addDoc(decl.Doc, spec.Name)
+					addDoc(spec.Doc, spec.Name)
+				case *ast.ValueSpec:
+					addDoc(decl.Doc, spec.Names...)
+					addDoc(spec.Doc, spec.Names...)
+				}
+			}
+		}
+	}
+
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			directive, ok := strings.CutPrefix(comment.Text, "//")
+			if !ok || !isRecordedDirective(directive) {
+				continue
+			}
+			nodes := documents[group]
+			if args, ok := strings.CutPrefix(directive, "go:linkname "); ok {
+				fields := strings.Fields(args)
+				nodes = nil
+				if len(fields) == 0 {
+					continue
+				}
+				if local, ok := g.Nodes[pkg.PkgPath+"."+fields[0]]; ok {
+					nodes = []*graph.Node{local}
+					if len(fields) > 1 {
+						if _, ok := g.Nodes[fields[1]]; ok {
+							links.Insert(local.Id, fields[1], graph.LinkLinkname)
+						}
+					}
+				}
+			}
+			if len(nodes) == 0 && strings.HasPrefix(directive, "go:generate ") {
+				if _, ok := g.Nodes[pkg.PkgPath]; !ok {
+					g.Nodes[pkg.PkgPath] = &graph.Node{
+						Kind:      graph.KindPackage,
+						Id:        pkg.PkgPath,
+						LocalName: pkg.PkgPath,
+						Pkg:       pkg.PkgPath,
+						Module:    modulePath(pkg),
+					}
+				}
+				nodes = []*graph.Node{g.Nodes[pkg.PkgPath]}
+			}
+			// Packages with tests are loaded twice, once with the test
+			// files
+			for _, node := range nodes {
+				if !slices.Contains(node.Directives, directive) {
+					node.Directives = append(node.Directives, directive)
+				}
+			}
+		}
+	}
+}
+
+// isRecordedDirective reports whether directive, a comment without the
+// leading //, is one of the recorded directives
+func isRecordedDirective(directive string) bool {
+	for _, name := range recordedDirectives {
+		if rest, ok := strings.CutPrefix(directive, name); ok && strings.HasPrefix(rest, " ") {
+			return true
+		}
+	}
+	return false
+}
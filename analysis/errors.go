@@ -0,0 +1,40 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// packageErrors reports the load and type errors of broken packages, keyed by
+// package ID, which tells test variants apart.
+type packageErrors map[string][]packages.Error
+
+// brokenPackages returns the errors of all packages in pkgs that failed to
+// load or type check
+func brokenPackages(pkgs []*packages.Package) packageErrors {
+	broken := make(packageErrors)
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			broken[pkg.ID] = pkg.Errors
+		}
+	}
+	return broken
+}
+
+func (e packageErrors) Error() string {
+	var report strings.Builder
+	report.WriteString("packages with errors:")
+	for _, id := range slices.Sorted(maps.Keys(e)) {
+		fmt.Fprintf(&report, "\n%s:", id)
+		for _, err := range e[id] {
+			fmt.Fprintf(&report, "\n\t%s", err)
+		}
+	}
+	return report.String()
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/phyrog/sgope/graph"
+)
+
+// addExampleEdges links every example function to the symbol it documents
+// according to its name: Example documents the package, ExampleF the
+// function F, ExampleT the type T and ExampleT_M the method M of T, each
+// optionally followed by a suffix starting with a lower-case letter, e.g.
+// ExampleT_M_second. Examples of external test packages document the package
+// under test.
+func (g *builder) addExampleEdges(links graph.LinkSet) {
+	var examples []*graph.Node
+	for _, node := range g.Nodes {
+		if node.Type == graph.FuncExample {
+			examples = append(examples, node)
+		}
+	}
+	for _, example := range examples {
+		pkgPath := strings.TrimSuffix(example.Pkg, "_test")
+		symbol, method := exampleSymbol(example.LocalName)
+		targetID := pkgPath
+		switch {
+		case method != "":
+			targetID = "(" + pkgPath + "." + symbol + ")." + method
+		case symbol != "":
+			targetID = pkgPath + "." + symbol
+		default:
+			if _, ok := g.Nodes[pkgPath]; !ok {
+				g.Nodes[pkgPath] = &graph.Node{
+					Kind:      graph.KindPackage,
+					Id:        pkgPath,
+					LocalName: pkgPath,
+					Pkg:       pkgPath,
+					Module:    example.Module,
+				}
+			}
+		}
+		if _, ok := g.Nodes[targetID]; ok {
+			links.Insert(example.Id, targetID, graph.LinkDemonstrates)
+		}
+	}
+}
+
+// exampleSymbol returns the names of the symbol and method documented by an
+// example function, both empty for package examples
+func exampleSymbol(name string) (symbol, method string) {
+	parts := strings.Split(strings.TrimPrefix(name, "Example"), "_")
+	// A part starting with a lower-case letter begins the suffix
+	for i, part := range parts {
+		if r, _ := utf8.DecodeRuneInString(part); part == "" || unicode.IsLower(r) {
+			parts = parts[:i]
+			break
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return "", ""
+	case 1:
+		return parts[0], ""
+	default:
+		return parts[0], parts[1]
+	}
+}
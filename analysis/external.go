@@ -0,0 +1,132 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/types"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// isStdPkg reports whether path is the import path of a standard library
+// package. Like the go command, it treats every path whose first element
+// has no dot as part of the standard library.
+func isStdPkg(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// externalNode returns the leaf node for obj, a package-level object or
+// method declared outside the analyzed packages, creating it if needed.
+// Struct fields and objects without a package are not represented.
+func (g *builder) externalNode(obj types.Object) *graph.Node {
+	if obj.Pkg() == nil {
+		return nil
+	}
+	if node := g.node(id(obj)); node != nil {
+		return node
+	}
+
+	node := &graph.Node{
+		Object:    obj,
+		Id:        id(obj),
+		LocalName: obj.Name(),
+		Pkg:       obj.Pkg().Path(),
+		External:  true,
+		Exported:  obj.Exported(),
+	}
+
+	switch t := obj.(type) {
+	case *types.Func:
+		node.Kind = graph.KindFunc
+		node.Type = graph.FuncBasic
+		node.Signature = funcSignature(t.Name(), t.Signature(), types.RelativeTo(t.Pkg()))
+		if recv := t.Type().(*types.Signature).Recv(); recv != nil {
+			recvType := recv.Type()
+			if ptr, ok := recvType.(*types.Pointer); ok {
+				recvType = ptr.Elem()
+			}
+			named, ok := types.Unalias(recvType).(*types.Named)
+			if !ok {
+				// Interface methods are reached through their interface
+				return nil
+			}
+			parent := g.externalNode(named.Obj())
+			if parent == nil {
+				return nil
+			}
+			node.Type = graph.FuncMethod
+			node.Parent = parent.Id
+			node.ReceiverPointer = hasPointerReceiver(t)
+			node.Exported = node.Exported && parent.Exported
+			node.LocalName = named.Obj().Name() + "." + t.Name()
+		}
+	case *types.TypeName:
+		node.Kind = graph.KindType
+		switch t.Type().Underlying().(type) {
+		case *types.Struct:
+			node.Type = graph.TypeStruct
+		case *types.Interface:
+			node.Type = graph.TypeInterface
+		case *types.Basic:
+			node.Type = graph.TypeBasic
+		case *types.Signature:
+			node.Type = graph.TypeFunc
+		default:
+			node.Type = graph.TypeName
+		}
+		if t.IsAlias() {
+			node.Type = graph.TypeAlias
+		}
+	case *types.Const:
+		node.Kind = graph.KindConst
+	case *types.Var:
+		if t.IsField() || t.Parent() != obj.Pkg().Scope() {
+			return nil
+		}
+		node.Kind = graph.KindVar
+		node.Type = graph.VarBasic
+	default:
+		return nil
+	}
+
+	return g.addNode(node)
+}
+
+// dependencyDepths returns the number of import hops from the initial
+// packages for every non-standard dependency up to maxDepth hops away, keyed
+// by package path. It also returns the loaded dependencies closer than
+// maxDepth, whose own references lead to the last hop.
+func dependencyDepths(initial []*packages.Package, maxDepth int) (map[string]int, []*packages.Package) {
+	depths := make(map[string]int)
+	for _, pkg := range initial {
+		depths[pkg.PkgPath] = 0
+	}
+
+	var deps []*packages.Package
+	queue := initial
+	for depth := 1; depth <= maxDepth && len(queue) > 0; depth++ {
+		var next []*packages.Package
+		for _, pkg := range queue {
+			for path, imp := range pkg.Imports {
+				if _, ok := depths[path]; ok || isStdPkg(path) {
+					continue
+				}
+				depths[path] = depth
+				// Only loaded packages can be analyzed further
+				if depth < maxDepth && imp.Types != nil && imp.TypesInfo != nil {
+					deps = append(deps, imp)
+					next = append(next, imp)
+				}
+			}
+		}
+		queue = next
+	}
+
+	for _, pkg := range initial {
+		delete(depths, pkg.PkgPath)
+	}
+	return depths, deps
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/ast"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// addFileNodes adds a node for every source file of pkg, identified by the
+// package path and file name, e.g. example.com/mod/pkg/file.go, linked to
+// the declarations in the file. Methods belong to the file declaring them
+// rather than to the file of their type, while fields, closures, grouped
+// constants and instances only belong to their parent. Files generated by
+// cgo preprocessing are skipped.
+func (g *builder) addFileNodes(pkg *packages.Package, links graph.LinkSet) {
+	files := make(map[string]string)
+	for _, file := range pkg.Syntax {
+		filename := pkg.Fset.File(file.Pos()).Name()
+		if !slices.Contains(pkg.GoFiles, filename) {
+			continue
+		}
+		pos := sourceRange(pkg, file.FileStart, file.FileEnd)
+		name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+		node := &graph.Node{
+			Kind:      graph.KindFile,
+			Id:        pkg.PkgPath + "/" + name,
+			LocalName: name,
+			Pkg:       pkg.PkgPath,
+			Module:    modulePath(pkg),
+			Test:      strings.HasSuffix(name, "_test.go"),
+			Position:  pos,
+			Generated: ast.IsGenerated(file),
+		}
+		g.Nodes[node.Id] = node
+		files[pos.File] = node.Id
+	}
+
+	for _, node := range g.Nodes {
+		if node.Pkg != pkg.PkgPath || node.Position == nil || node.Kind == graph.KindFile {
+			continue
+		}
+		if node.Parent != "" && node.Type != graph.FuncMethod {
+			continue
+		}
+		// Packages with tests are loaded twice, once with the test files,
+		// but files contain their declarations only once
+		if fileID, ok := files[node.Position.File]; ok && links[graph.LinkKey{From: fileID, To: node.Id, Kind: graph.LinkContains}] == 0 {
+			links.Insert(fileID, node.Id, graph.LinkContains)
+		}
+	}
+}
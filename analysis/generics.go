@@ -0,0 +1,170 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// typeParams returns the type parameters declared by obj, if it is a generic
+// function or type.
+func typeParams(obj types.Object) *types.TypeParamList {
+	switch t := obj.(type) {
+	case *types.Func:
+		return t.Type().(*types.Signature).TypeParams()
+	case *types.TypeName:
+		if named, ok := t.Type().(*types.Named); ok {
+			return named.TypeParams()
+		}
+	}
+	return nil
+}
+
+// addConstraintEdges links every generic function and type to the named
+// types its type parameter constraints refer to, e.g. the constraint
+// interface in [T fmt.Stringer] or the terms of [T ~int | MyInt].
+func (g *builder) addConstraintEdges(links graph.LinkSet) {
+	for _, node := range g.Nodes {
+		if node.Object == nil {
+			continue
+		}
+		tparams := typeParams(node.Object)
+		for tparam := range tparams.TypeParams() {
+			for _, named := range constraintTypes(tparam.Constraint()) {
+				if target, ok := g.Nodes[id(named.Obj())]; ok {
+					links.Insert(node.Id, target.Id, graph.LinkConstraint)
+				}
+			}
+		}
+	}
+}
+
+// constraintTypes returns the named types mentioned by a type parameter
+// constraint, looking into inline constraint interfaces and unions.
+func constraintTypes(t types.Type) []*types.Named {
+	switch t := t.(type) {
+	case *types.Named:
+		return []*types.Named{t}
+	case *types.Interface:
+		var named []*types.Named
+		for embedded := range t.EmbeddedTypes() {
+			named = append(named, constraintTypes(embedded)...)
+		}
+		return named
+	case *types.Union:
+		var named []*types.Named
+		for term := range t.Terms() {
+			named = append(named, constraintTypes(term.Type())...)
+		}
+		return named
+	}
+	return nil
+}
+
+// addInstanceNodes emits a node for every concrete instantiation of a generic
+// function or type in pkg, e.g. pkg.List[int], linked to its generic origin.
+// Instantiations with type parameters as arguments, which only occur inside
+// generic code, are skipped.
+func (g *builder) addInstanceNodes(pkg *packages.Package, links graph.LinkSet) {
+	for ident, inst := range pkg.TypesInfo.Instances {
+		obj := pkg.TypesInfo.Uses[ident]
+		if obj == nil {
+			continue
+		}
+		origin, ok := g.Nodes[id(obj)]
+		if !ok || hasTypeParams(inst.TypeArgs) {
+			continue
+		}
+
+		instID := origin.Id + typeArgsString(inst.TypeArgs, nil)
+		if _, ok := g.Nodes[instID]; ok {
+			continue
+		}
+		g.Nodes[instID] = &graph.Node{
+			Package:   origin.Package,
+			Kind:      origin.Kind,
+			Type:      origin.Type,
+			Id:        instID,
+			Parent:    origin.Id,
+			LocalName: origin.LocalName + typeArgsString(inst.TypeArgs, types.RelativeTo(obj.Pkg())),
+			Pkg:       origin.Pkg,
+			Module:    origin.Module,
+			Position:  origin.Position,
+			Test:      origin.Test,
+			Exported:  origin.Exported,
+			Generated: origin.Generated,
+			Broken:    origin.Broken,
+		}
+		if sig, ok := inst.Type.(*types.Signature); ok {
+			g.Nodes[instID].Signature = funcSignature(obj.Name(), sig, types.RelativeTo(obj.Pkg()))
+		}
+		links.Insert(instID, origin.Id, graph.LinkInstantiates)
+	}
+}
+
+// instanceNodeID returns the ID of the instance node for a reference to a
+// generic function or type by ident, or an empty string if ident does not
+// instantiate anything.
+func instanceNodeID(pkg *packages.Package, ident *ast.Ident, obj types.Object) string {
+	inst, ok := pkg.TypesInfo.Instances[ident]
+	if !ok {
+		return ""
+	}
+	return id(obj) + typeArgsString(inst.TypeArgs, nil)
+}
+
+func typeArgsString(targs *types.TypeList, qf types.Qualifier) string {
+	var args []string
+	for t := range targs.Types() {
+		args = append(args, types.TypeString(t, qf))
+	}
+	return "[" + strings.Join(args, ",") + "]"
+}
+
+func hasTypeParams(targs *types.TypeList) bool {
+	for t := range targs.Types() {
+		if containsTypeParam(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTypeParam(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Pointer:
+		return containsTypeParam(t.Elem())
+	case *types.Slice:
+		return containsTypeParam(t.Elem())
+	case *types.Array:
+		return containsTypeParam(t.Elem())
+	case *types.Chan:
+		return containsTypeParam(t.Elem())
+	case *types.Map:
+		return containsTypeParam(t.Key()) || containsTypeParam(t.Elem())
+	case *types.Named:
+		return hasTypeParams(t.TypeArgs())
+	case *types.Tuple:
+		for v := range t.Variables() {
+			if containsTypeParam(v.Type()) {
+				return true
+			}
+		}
+	case *types.Signature:
+		return containsTypeParam(t.Params()) || containsTypeParam(t.Results())
+	case *types.Struct:
+		for field := range t.Fields() {
+			if containsTypeParam(field.Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"path"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// addHierarchy adds a node for every analyzed package and for the
+// directories between it and the root of its module, linking every
+// directory to the packages and directories below it, and every package to
+// its files if the graph has file nodes or its top-level declarations
+// otherwise. External test packages are placed below the package they test.
+func (g *builder) addHierarchy(pkgs []*packages.Package, links graph.LinkSet) {
+	analyzed := make(map[string]*packages.Package)
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.PkgPath, ".test") || pkg.Module == nil {
+			continue
+		}
+		if _, ok := analyzed[pkg.PkgPath]; !ok {
+			analyzed[pkg.PkgPath] = pkg
+		}
+	}
+	contain := func(from, to string) {
+		if links[graph.LinkKey{From: from, To: to, Kind: graph.LinkContains}] == 0 {
+			links.Insert(from, to, graph.LinkContains)
+		}
+	}
+	addNode := func(pkgPath, typ string, pkg *packages.Package) {
+		if _, ok := g.Nodes[pkgPath]; ok {
+			return
+		}
+		g.Nodes[pkgPath] = &graph.Node{
+			Kind:      graph.KindPackage,
+			Type:      typ,
+			Id:        pkgPath,
+			LocalName: pkgPath,
+			Pkg:       pkgPath,
+			Module:    modulePath(pkg),
+		}
+	}
+
+	for pkgPath, pkg := range analyzed {
+		addNode(pkgPath, "", pkg)
+		child := pkgPath
+		parent := path.Dir(pkgPath)
+		if tested, ok := strings.CutSuffix(pkgPath, "_test"); ok && analyzed[tested] != nil {
+			parent = tested
+		}
+		for child != pkg.Module.Path && (parent == pkg.Module.Path || strings.HasPrefix(parent, pkg.Module.Path+"/")) {
+			if analyzed[parent] != nil {
+				addNode(parent, "", analyzed[parent])
+			} else {
+				addNode(parent, graph.PkgDirectory, pkg)
+			}
+			contain(parent, child)
+			child, parent = parent, path.Dir(parent)
+		}
+	}
+
+	hasFiles := make(map[string]bool)
+	for _, node := range g.Nodes {
+		if node.Kind == graph.KindFile {
+			hasFiles[node.Pkg] = true
+		}
+	}
+	for _, node := range g.Nodes {
+		if analyzed[node.Pkg] == nil || node.Kind == graph.KindPackage || node.Kind == graph.KindComponent {
+			continue
+		}
+		switch {
+		case node.Kind == graph.KindFile:
+			contain(node.Pkg, node.Id)
+		case hasFiles[node.Pkg]:
+			continue
+		case node.Parent == "" || node.Type == graph.FuncMethod:
+			contain(node.Pkg, node.Id)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// addMetrics records the number of lines and the cyclomatic complexity of
+// every function, method and closure node declared in file.
+func (g *builder) addMetrics(pkg *packages.Package, file *ast.File) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		var nodeID string
+		var body *ast.BlockStmt
+		switch decl := n.(type) {
+		case *ast.FuncDecl:
+			if obj := pkg.TypesInfo.Defs[decl.Name]; obj != nil {
+				nodeID = g.objID(obj)
+			}
+			body = decl.Body
+		case *ast.FuncLit:
+			nodeID = g.closures[decl]
+			body = decl.Body
+		default:
+			return true
+		}
+
+		node, ok := g.Nodes[nodeID]
+		if !ok || body == nil {
+			return true
+		}
+		node.Lines = pkg.Fset.Position(n.End()).Line - pkg.Fset.Position(n.Pos()).Line + 1
+		node.Complexity = cyclomaticComplexity(body)
+		return true
+	})
+}
+
+// cyclomaticComplexity returns one plus the number of decision points in
+// body: conditions, loops, non-default cases and short-circuit operators.
+// Like gocyclo, function literals count towards the enclosing function.
+func cyclomaticComplexity(body *ast.BlockStmt) int {
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
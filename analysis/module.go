@@ -0,0 +1,69 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+)
+
+// isVersioned reports whether path is a package or module path with a
+// version, e.g. golang.org/x/text@v0.14.0
+func isVersioned(path string) bool {
+	return strings.Contains(path, "@")
+}
+
+// analyzeVersions analyzes the packages of paths with a version, e.g.
+// golang.org/x/text@v0.14.0, in a temporary module requiring their modules,
+// which the go command downloads into the module cache if needed. A module
+// path stands for all packages of the module.
+func analyzeVersions(opts *Options, paths ...string) (*graph.Graph, error) {
+	dir, err := os.MkdirTemp("", "sgope-mod-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if _, err := runGo(dir, opts.Env, "mod", "init", "sgope.local/scratch"); err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, path := range paths {
+		if !isVersioned(path) {
+			return nil, fmt.Errorf("cannot analyze %s together with versioned package paths", path)
+		}
+		if _, err := runGo(dir, opts.Env, "get", path); err != nil {
+			return nil, err
+		}
+		pattern, _, _ := strings.Cut(path, "@")
+		if _, err := runGo(dir, opts.Env, "list", "-m", pattern); err == nil {
+			pattern += "/..."
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	modOpts := *opts
+	modOpts.Dir = dir
+	g, err := Analyze(&modOpts, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", strings.Join(paths, " "), err)
+	}
+	return g, nil
+}
+
+// runGo runs a go command in dir with the additional environment variables
+// env and returns its trimmed output
+func runGo(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("go %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// forEachPackage calls fn for every package of pkgs on a pool of up to
+// GOMAXPROCS goroutines and waits for all calls to return. fn gets the index
+// of the package to store its results without synchronization.
+func forEachPackage(pkgs []*packages.Package, fn func(i int, pkg *packages.Package)) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(pkgs)) {
+		wg.Go(func() {
+			for i := range next {
+				fn(i, pkgs[i])
+			}
+		})
+	}
+	for i := range pkgs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// node returns the node with the given ID, or nil if there is none. Unlike
+// reading Nodes directly, it is safe while packages are processed in
+// parallel.
+func (g *builder) node(nodeID string) *graph.Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Nodes[nodeID]
+}
+
+// addNode adds node to the graph unless there already is a node with its ID,
+// and returns the node in the graph. It is safe while packages are processed
+// in parallel.
+func (g *builder) addNode(node *graph.Node) *graph.Node {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if existing, ok := g.Nodes[node.Id]; ok {
+		return existing
+	}
+	g.Nodes[node.Id] = node
+	return node
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+)
+
+// parsePlatforms parses a comma-separated list of GOOS/GOARCH pairs
+func parsePlatforms(s string) ([][2]string, error) {
+	var platforms [][2]string
+	for p := range strings.SplitSeq(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		goos, goarch, ok := strings.Cut(p, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("invalid platform %q, expected GOOS/GOARCH", p)
+		}
+		platforms = append(platforms, [2]string{goos, goarch})
+	}
+	return platforms, nil
+}
+
+// analyzePlatforms analyzes the packages once per platform and returns the
+// union of the graphs. Every node is annotated with the platforms it exists
+// on.
+func analyzePlatforms(opts *Options, paths ...string) (*graph.Graph, error) {
+	platforms, err := parsePlatforms(opts.Platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	var union *graph.Graph
+	for _, platform := range platforms {
+		platformOpts := *opts
+		platformOpts.Platforms = ""
+		// Tests are mapped, cycles condensed, links reduced, centrality
+		// computed, the graph focused, pruned and collapsed and IDs
+		// shortened once all graphs are merged
+		platformOpts.TestedBy = false
+		platformOpts.Condense = false
+		platformOpts.Reduce = false
+		platformOpts.Centrality = false
+		platformOpts.Focus = nil
+		platformOpts.MinWeight, platformOpts.TopLinks = 0, 0
+		platformOpts.MaxNodes = 0
+		platformOpts.ShortIDs = false
+		platformOpts.GOOS, platformOpts.GOARCH = platform[0], platform[1]
+
+		g, err := Analyze(&platformOpts, paths...)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", platform[0], platform[1], err)
+		}
+		name := platform[0] + "/" + platform[1]
+		for _, node := range g.Nodes {
+			node.Platforms = []string{name}
+		}
+
+		if union == nil {
+			union = g
+		} else {
+			union.Merge(g)
+		}
+	}
+	union.AddCohesion()
+	if opts.TestedBy {
+		union.AddTestedBy()
+	}
+	if opts.Condense {
+		union.Condense()
+	}
+	if opts.Reduce {
+		union.Reduce()
+	}
+	union.AddFanMetrics()
+	if opts.Centrality {
+		union.AddCentrality()
+	}
+	if len(opts.Focus) > 0 {
+		if union, err = focus(union, opts.Focus, opts.FocusDepth); err != nil {
+			return nil, err
+		}
+	}
+	union.PruneLinks(opts.MinWeight, opts.TopLinks)
+	union.Collapse(opts.MaxNodes)
+	if opts.ShortIDs {
+		union.ShortenIDs()
+	}
+	return union, nil
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+)
+
+// pluginOutput is what a plugin writes to stdout: nodes and links to add to
+// the graph and attributes to set on existing nodes
+type pluginOutput struct {
+	Nodes []*graph.Node `json:"nodes"`
+	Links []graph.Link  `json:"links"`
+	// Attributes maps node IDs to the attributes to set on them
+	Attributes map[string]map[string]any `json:"attributes"`
+}
+
+// runPlugin runs an external analyzer and merges its contributions into g.
+// The command line is split at spaces and run in dir. The plugin receives
+// the graph as JSON on stdin, with positions as objects, and writes a
+// pluginOutput as JSON to stdout. Added nodes must not exist yet and added
+// links must connect nodes of the graph.
+func (g *builder) runPlugin(command, dir string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return fmt.Errorf("empty plugin command")
+	}
+
+	in := *g.Graph
+	in.PositionStrings = false
+	input, err := json.Marshal(&in)
+	if err != nil {
+		return fmt.Errorf("JSON marshaling error: %w", err)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	var contrib pluginOutput
+	if err := json.Unmarshal(out, &contrib); err != nil {
+		return fmt.Errorf("plugin %s: malformed output: %w", args[0], err)
+	}
+	for _, node := range contrib.Nodes {
+		if node.Id == "" {
+			return fmt.Errorf("plugin %s: node without id", args[0])
+		}
+		if _, ok := g.Nodes[node.Id]; ok {
+			return fmt.Errorf("plugin %s: node %s already exists", args[0], node.Id)
+		}
+		g.Nodes[node.Id] = node
+	}
+	for _, link := range contrib.Links {
+		if _, ok := g.Nodes[link.From]; !ok {
+			return fmt.Errorf("plugin %s: link from unknown node %s", args[0], link.From)
+		}
+		if _, ok := g.Nodes[link.To]; !ok {
+			return fmt.Errorf("plugin %s: link to unknown node %s", args[0], link.To)
+		}
+		link.Weight = max(link.Weight, 1)
+		g.Links = append(g.Links, link)
+	}
+	for nodeID, attrs := range contrib.Attributes {
+		node, ok := g.Nodes[nodeID]
+		if !ok {
+			return fmt.Errorf("plugin %s: attributes for unknown node %s", args[0], nodeID)
+		}
+		if node.Attributes == nil {
+			node.Attributes = make(map[string]any, len(attrs))
+		}
+		for key, value := range attrs {
+			node.Attributes[key] = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum time between progress reports within a
+// phase of the analysis
+const progressInterval = time.Second
+
+// progress reports the progress of an analysis as lines written to w. A nil
+// progress or one without a writer reports nothing.
+type progress struct {
+	w    io.Writer
+	mu   sync.Mutex
+	last time.Time
+}
+
+// report writes a progress line
+func (p *progress) report(format string, args ...any) {
+	if p == nil || p.w == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last = time.Now()
+	fmt.Fprintf(p.w, format+"\n", args...)
+}
+
+// update writes a progress line unless the last one was written less than
+// progressInterval ago, for reports made for every package
+func (p *progress) update(format string, args ...any) {
+	if p == nil || p.w == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.last) < progressInterval {
+		return
+	}
+	p.last = time.Now()
+	fmt.Fprintf(p.w, format+"\n", args...)
+}
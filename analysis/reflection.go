@@ -0,0 +1,75 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// markReflected marks the types of the analyzed code that pkg passes to
+// functions and methods of reflect or an encoding/* package, e.g.
+// reflect.TypeOf or json.Unmarshal, along with the types of their fields,
+// since those inspect fields and methods that no link leads to.
+func (g *builder) markReflected(pkg *packages.Package) {
+	seen := make(map[*types.Named]bool)
+	var mark func(typ types.Type)
+	mark = func(typ types.Type) {
+		for _, t := range underlyingTypes(typ) {
+			named, ok := types.Unalias(t).(*types.Named)
+			if !ok {
+				if st, ok := t.(*types.Struct); ok {
+					for field := range st.Fields() {
+						mark(field.Type())
+					}
+				}
+				continue
+			}
+			named = named.Origin()
+			if seen[named] {
+				continue
+			}
+			seen[named] = true
+			node, ok := g.Nodes[id(named.Obj())]
+			if !ok {
+				continue
+			}
+			node.Reflected = true
+			if st, ok := named.Underlying().(*types.Struct); ok {
+				for field := range st.Fields() {
+					mark(field.Type())
+				}
+			}
+		}
+	}
+
+	for _, file := range g.syntax(pkg) {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !isReflectiveCall(pkg, call) {
+				return true
+			}
+			for _, arg := range call.Args {
+				if typ := pkg.TypesInfo.TypeOf(arg); typ != nil {
+					mark(typ)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// isReflectiveCall reports whether call calls a function or method of the
+// reflect package or of an encoding/* package
+func isReflectiveCall(pkg *packages.Package, call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(pkg.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return false
+	}
+	path := fn.Pkg().Path()
+	return path == "reflect" || strings.HasPrefix(path, "encoding/")
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/internal/git"
+)
+
+// analyzeRevision checks out the git revision opts.rev of the repository
+// containing the working directory into a temporary worktree and analyzes
+// the packages there. Package paths are resolved relative to the
+// corresponding directory of the worktree.
+func analyzeRevision(opts *Options, paths ...string) (*graph.Graph, error) {
+	dir := opts.Dir
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	top, err := git.Run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(top, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := os.MkdirTemp("", "sgope-rev-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(worktree)
+	if _, err := git.Run(top, "worktree", "add", "--detach", worktree, opts.Rev); err != nil {
+		return nil, err
+	}
+	defer git.Run(top, "worktree", "remove", "--force", worktree)
+
+	revOpts := *opts
+	revOpts.Rev = ""
+	revOpts.Dir = filepath.Join(worktree, rel)
+	g, err := Analyze(&revOpts, paths...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", opts.Rev, err)
+	}
+	return g, nil
+}
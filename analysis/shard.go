@@ -0,0 +1,77 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// Shard selects one of Count disjoint sets of packages, numbered from 1.
+// Packages are assigned to shards by a hash of their path, so adding a
+// package does not move the others.
+type Shard struct {
+	Index, Count int
+}
+
+// String formats s as "index/count"
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// contains reports whether the package with the given path belongs to s
+func (s Shard) contains(pkgPath string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(pkgPath))
+	return int(h.Sum32()%uint32(s.Count)) == s.Index-1
+}
+
+// analyzeShard analyzes the packages matching paths that belong to the shard
+// of opts. Declarations of the packages of other shards that they refer to
+// are leaf nodes, which graph.Merge replaces by the nodes of the shard they
+// belong to.
+func analyzeShard(opts *Options, paths ...string) (*graph.Graph, error) {
+	if opts.Shard.Index < 1 || opts.Shard.Index > opts.Shard.Count {
+		return nil, fmt.Errorf("invalid shard %s", opts.Shard)
+	}
+	options := opts.wholeGraphOptions()
+	options["ChangedSince"] = opts.ChangedSince != ""
+	if err := unsupported("Shard", options); err != nil {
+		return nil, err
+	}
+	if opts.Workspace {
+		patterns, err := workspacePatterns(opts.Dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, patterns...)
+	}
+
+	cfg := opts.packagesConfig()
+	cfg.Mode = packages.NeedName
+	cfg.Tests = false
+	pkgs, err := packages.Load(cfg, paths...)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]bool, len(pkgs))
+	var shard []string
+	for _, pkg := range pkgs {
+		all[pkg.PkgPath] = true
+		if opts.Shard.contains(pkg.PkgPath) {
+			shard = append(shard, pkg.PkgPath)
+		}
+	}
+
+	shardOpts := *opts
+	shardOpts.Shard = Shard{}
+	shardOpts.Workspace = false
+	shardOpts.leafPkgs = all
+	if len(shard) == 0 {
+		return &graph.Graph{Nodes: make(map[string]*graph.Node), PositionStrings: opts.PositionStrings}, nil
+	}
+	return Analyze(&shardOpts, shard...)
+}
@@ -0,0 +1,190 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// lacksTypes reports whether pkg failed to load to the point that it has no
+// usable type information, e.g. because go list rejected it or cgo
+// preprocessing failed.
+func lacksTypes(pkg *packages.Package) bool {
+	return pkg.Types == nil || pkg.TypesInfo == nil || pkg.IllTyped && pkg.Types.Scope().Len() == 0
+}
+
+// syntaxNodes returns nodes for the declarations of pkg derived from its
+// syntax alone. Files the loader did not parse are parsed here, tolerating
+// syntax errors. Without type information, references cannot be resolved,
+// so the nodes are only linked to their parents.
+func syntaxNodes(pkg *packages.Package, links graph.LinkSet) []*graph.Node {
+	files := pkg.Syntax
+	if len(files) == 0 {
+		for _, filename := range pkg.GoFiles {
+			// Partial syntax trees are returned along with parse errors
+			file, _ := parser.ParseFile(pkg.Fset, filename, nil, parser.SkipObjectResolution)
+			if file != nil {
+				files = append(files, file)
+			}
+		}
+	}
+
+	var nodes []*graph.Node
+	add := func(node *graph.Node, start, end token.Pos) {
+		node.Package = pkg
+		node.Pkg = pkg.PkgPath
+		node.Module = modulePath(pkg)
+		node.Position = sourceRange(pkg, start, end)
+		node.Test = strings.HasSuffix(pkg.Fset.Position(start).Filename, "_test.go")
+		node.Broken = true
+		nodes = append(nodes, node)
+	}
+	inits := 0
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				node := &graph.Node{
+					Kind:      graph.KindFunc,
+					Type:      graph.FuncBasic,
+					Id:        pkg.PkgPath + "." + decl.Name.Name,
+					LocalName: decl.Name.Name,
+					Exported:  decl.Name.IsExported(),
+				}
+				if decl.Recv != nil && len(decl.Recv.List) > 0 {
+					recv, pointer := receiverTypeName(decl.Recv.List[0].Type)
+					if recv == "" {
+						continue
+					}
+					node.Type = graph.FuncMethod
+					node.Parent = pkg.PkgPath + "." + recv
+					node.Id = "(" + node.Parent + ")." + decl.Name.Name
+					node.LocalName = recv + "." + decl.Name.Name
+					node.Exported = node.Exported && ast.IsExported(recv)
+					node.ReceiverPointer = pointer
+					links.Insert(node.Id, node.Parent, graph.LinkMethodOf)
+				} else if decl.Name.Name == "init" {
+					inits++
+					node.LocalName = fmt.Sprintf("init#%d", inits)
+					node.Id = pkg.PkgPath + "." + node.LocalName
+				}
+				add(node, decl.Pos(), decl.End())
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						typeID := pkg.PkgPath + "." + spec.Name.Name
+						node := &graph.Node{
+							Kind:      graph.KindType,
+							Type:      graph.TypeName,
+							Id:        typeID,
+							LocalName: spec.Name.Name,
+							Exported:  spec.Name.IsExported(),
+						}
+						// Members are collected as fields, which hold both the
+						// fields of structs and the methods of interfaces
+						var members []*ast.Field
+						switch t := spec.Type.(type) {
+						case *ast.StructType:
+							node.Type = graph.TypeStruct
+							members = t.Fields.List
+						case *ast.InterfaceType:
+							node.Type = graph.TypeInterface
+							members = t.Methods.List
+						case *ast.FuncType:
+							node.Type = graph.TypeFunc
+						}
+						if spec.Assign.IsValid() {
+							node.Type = graph.TypeAlias
+							members = nil
+						}
+						add(node, spec.Pos(), spec.End())
+
+						for _, member := range members {
+							names := member.Names
+							if len(names) == 0 {
+								// Embedded fields are named after their type,
+								// embedded interfaces are not members
+								name, _ := receiverTypeName(member.Type)
+								if name == "" || node.Type == graph.TypeInterface {
+									continue
+								}
+								names = []*ast.Ident{ast.NewIdent(name)}
+							}
+							for _, name := range names {
+								memberNode := &graph.Node{
+									Kind:      graph.KindVar,
+									Type:      graph.VarField,
+									Id:        "(" + typeID + ")." + name.Name,
+									Parent:    typeID,
+									LocalName: spec.Name.Name + "." + name.Name,
+									Exported:  node.Exported && name.IsExported(),
+								}
+								kind := graph.LinkParent
+								if node.Type == graph.TypeInterface {
+									memberNode.Kind = graph.KindFunc
+									memberNode.Type = graph.FuncMethod
+									kind = graph.LinkMethodOf
+								}
+								add(memberNode, member.Pos(), member.End())
+								links.Insert(memberNode.Id, typeID, kind)
+							}
+						}
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							if name.Name == "_" {
+								continue
+							}
+							node := &graph.Node{
+								Kind:      graph.KindVar,
+								Type:      graph.VarBasic,
+								Id:        pkg.PkgPath + "." + name.Name,
+								LocalName: name.Name,
+								Exported:  name.IsExported(),
+							}
+							if decl.Tok == token.CONST {
+								node.Kind = graph.KindConst
+								node.Type = ""
+							}
+							add(node, spec.Pos(), spec.End())
+						}
+					}
+				}
+			}
+		}
+	}
+	return nodes
+}
+
+// receiverTypeName returns the name of the type in a receiver or embedded
+// field type expression, e.g. T for *T, T[K] or pkg.T, and whether it is a
+// pointer.
+func receiverTypeName(expr ast.Expr) (name string, pointer bool) {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+			pointer = true
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			return e.Sel.Name, pointer
+		case *ast.Ident:
+			return e.Name, pointer
+		default:
+			return "", pointer
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"golang.org/x/tools/go/packages"
+)
+
+// TrimPrefix selects the import path prefixes to trim from node IDs if
+// enabled, the given prefix or by default the paths of the analyzed modules
+type TrimPrefix struct {
+	Enabled bool
+	Prefix  string
+}
+
+// prefixes returns the import path prefixes to trim, defaulting to the paths
+// of the main modules of pkgs
+func (t TrimPrefix) prefixes(pkgs []*packages.Package) []string {
+	if !t.Enabled {
+		return nil
+	}
+	if t.Prefix != "" {
+		return []string{t.Prefix}
+	}
+	var prefixes []string
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.Module != nil && pkg.Module.Main && !seen[pkg.Module.Path] {
+			seen[pkg.Module.Path] = true
+			prefixes = append(prefixes, pkg.Module.Path)
+		}
+	}
+	return prefixes
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"go/ast"
+	"go/types"
+	"slices"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// markUnsafe marks the nodes of pkg whose declarations refer to the unsafe
+// package or to C symbols through cgo
+func (g *builder) markUnsafe(pkg *packages.Package) {
+	for _, file := range g.syntax(pkg) {
+		ast.Inspect(file, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := pkg.TypesInfo.Uses[ident]
+			if obj == nil {
+				return true
+			}
+			isUnsafe := obj.Pkg() == types.Unsafe
+			// C.name refers to the C package before cgo preprocessing and to
+			// a generated declaration after it
+			isCgo := obj.Pkg() == pkg.Types && isCgoGenerated(pkg, obj)
+			if pkgName, ok := obj.(*types.PkgName); ok {
+				switch pkgName.Imported().Path() {
+				case "unsafe":
+					isUnsafe = true
+				case cgoPkg:
+					isCgo = true
+				}
+			}
+			if !isUnsafe && !isCgo {
+				return true
+			}
+			for _, node := range g.findContainingNodes(pkg, file, ident) {
+				node.Unsafe = node.Unsafe || isUnsafe
+				node.Cgo = node.Cgo || isCgo
+			}
+			return true
+		})
+	}
+}
+
+// keepUnsafe drops all nodes that neither use unsafe or cgo nor depend on a
+// node that does, keeping the C symbols they refer to
+func (g *builder) keepUnsafe() {
+	keep := g.UnsafeDependents()
+	for nodeID, node := range g.Nodes {
+		if _, ok := keep[nodeID]; ok || node.Unsafe || node.Cgo || node.Pkg == cgoPkg {
+			continue
+		}
+		delete(g.Nodes, nodeID)
+	}
+	g.Links = slices.DeleteFunc(g.Links, func(link graph.Link) bool {
+		_, fromOK := g.Nodes[link.From]
+		_, toOK := g.Nodes[link.To]
+		return !fromOK || !toOK
+	})
+}
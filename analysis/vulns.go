@@ -0,0 +1,149 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// vulnFinding is a call of a vulnerable symbol reported by govulncheck
+type vulnFinding struct {
+	OSV string
+	// Trace lists the node IDs of the call stack from the vulnerable symbol
+	// to the entry point in the analyzed code
+	Trace []string
+}
+
+// loadVulns reads govulncheck -json output from path, or runs govulncheck on
+// the package patterns in dir if path is "-".
+func loadVulns(path, dir string, patterns []string) ([]vulnFinding, error) {
+	var data []byte
+	if path == "-" {
+		cmd := exec.Command("govulncheck", append([]string{"-json"}, patterns...)...)
+		cmd.Dir = dir
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		// govulncheck may exit with a non-zero status when it finds
+		// vulnerabilities, so only fail without output
+		if err != nil && len(out) == 0 {
+			if errors.Is(err, exec.ErrNotFound) {
+				return nil, fmt.Errorf("govulncheck not found, install it with go install golang.org/x/vuln/cmd/govulncheck@latest or pass its -json output with -vulns")
+			}
+			return nil, fmt.Errorf("govulncheck: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		data = out
+	} else {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return parseVulns(bytes.NewReader(data))
+}
+
+// parseVulns parses the stream of JSON messages written by govulncheck -json
+// and returns the findings at symbol level, i.e. those with a known call of a
+// vulnerable function.
+func parseVulns(r io.Reader) ([]vulnFinding, error) {
+	type frame struct {
+		Package  string `json:"package"`
+		Function string `json:"function"`
+		Receiver string `json:"receiver"`
+	}
+	var findings []vulnFinding
+	dec := json.NewDecoder(r)
+	for {
+		var msg struct {
+			Finding *struct {
+				OSV   string  `json:"osv"`
+				Trace []frame `json:"trace"`
+			} `json:"finding"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("malformed govulncheck output: %w", err)
+		}
+		if msg.Finding == nil || len(msg.Finding.Trace) == 0 || msg.Finding.Trace[0].Function == "" {
+			continue
+		}
+		finding := vulnFinding{OSV: msg.Finding.OSV}
+		for _, f := range msg.Finding.Trace {
+			if f.Function == "" {
+				continue
+			}
+			finding.Trace = append(finding.Trace, vulnSymbolID(f.Package, f.Receiver, f.Function))
+		}
+		findings = append(findings, finding)
+	}
+	return findings, nil
+}
+
+// vulnSymbolID returns the node ID of a function or method named by
+// govulncheck. Receivers may be pointers or carry type arguments, which node
+// IDs leave out.
+func vulnSymbolID(pkg, receiver, function string) string {
+	if receiver == "" {
+		return pkg + "." + function
+	}
+	receiver = strings.TrimPrefix(receiver, "*")
+	if i := strings.Index(receiver, "["); i >= 0 {
+		receiver = receiver[:i]
+	}
+	return "(" + pkg + "." + receiver + ")." + function
+}
+
+// markVulns marks the nodes reached by the calls of vulnerable symbols in
+// findings. The vulnerable symbol of a finding, or the first frame of its
+// trace that is part of the graph if the symbol is not, is marked as
+// vulnerable, and every node it can be reached from as reaching the
+// vulnerability.
+func (g *builder) markVulns(findings []vulnFinding) {
+	dependents := make(map[string][]string)
+	for _, link := range g.Links {
+		dependents[link.To] = append(dependents[link.To], link.From)
+	}
+	addOSV := func(list []string, osv string) []string {
+		if slices.Contains(list, osv) {
+			return list
+		}
+		return append(list, osv)
+	}
+
+	for _, finding := range findings {
+		i := slices.IndexFunc(finding.Trace, func(nodeID string) bool {
+			_, ok := g.Nodes[nodeID]
+			return ok
+		})
+		if i < 0 {
+			continue
+		}
+		target := g.Nodes[finding.Trace[i]]
+		target.Vulnerable = addOSV(target.Vulnerable, finding.OSV)
+
+		seen := map[string]bool{target.Id: true}
+		queue := []string{target.Id}
+		for len(queue) > 0 {
+			nodeID := queue[0]
+			queue = queue[1:]
+			for _, from := range dependents[nodeID] {
+				node, ok := g.Nodes[from]
+				if !ok || seen[from] {
+					continue
+				}
+				seen[from] = true
+				queue = append(queue, from)
+				node.Vulns = addOSV(node.Vulns, finding.OSV)
+			}
+		}
+	}
+}
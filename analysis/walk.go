@@ -0,0 +1,165 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// Walk analyzes the packages matching paths one at a time and passes the
+// nodes and links of each package to visitNode and visitLink before loading
+// the next, so the graph of large repositories is never held in memory as a
+// whole. Declarations of other walked packages are visited with their own
+// package, symbols of the standard library and dependencies once.
+//
+// Links that can only be found by analyzing packages together are missing,
+// e.g. implementations of interfaces of packages the implementing package
+// does not import, blank imports and dynamic calls into other packages.
+// Metrics counting links, e.g. fan-in, only count the links of each
+// package. Options that need the whole graph are not supported.
+func Walk(opts *Options, paths []string, visitNode func(node *graph.Node), visitLink func(link graph.Link)) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	options := opts.wholeGraphOptions()
+	options["Rev"] = opts.Rev != ""
+	options["Platforms"] = opts.Platforms != ""
+	options["Hierarchy"] = opts.Hierarchy
+	options["Plugins"] = len(opts.Plugins) > 0
+	options["Shard"] = opts.Shard.Count > 0
+	options["ChangedSince"] = opts.ChangedSince != ""
+	if err := unsupported("Walk", options); err != nil {
+		return err
+	}
+
+	if opts.Workspace {
+		patterns, err := workspacePatterns(opts.Dir)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, patterns...)
+	}
+
+	cfg := opts.packagesConfig()
+	cfg.Mode = packages.NeedName
+	cfg.Tests = false
+	pkgs, err := packages.Load(cfg, paths...)
+	if err != nil {
+		return err
+	}
+	walked := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		walked[pkg.PkgPath] = true
+	}
+
+	// Symbols outside the walked packages may be referred to by several of
+	// them
+	seenNodes := make(map[string]bool)
+	seenLinks := make(map[graph.LinkKey]bool)
+	for _, pkg := range pkgs {
+		pkgOpts := *opts
+		pkgOpts.Workspace = false
+		pkgOpts.leafPkgs = walked
+		g, err := Analyze(&pkgOpts, pkg.PkgPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pkg.PkgPath, err)
+		}
+
+		owned := make(map[string]bool)
+		for nodeID, node := range g.Nodes {
+			if !node.External {
+				owned[nodeID] = true
+				visitNode(node)
+				continue
+			}
+			if node.Object != nil && node.Object.Pkg() != nil && walked[node.Object.Pkg().Path()] {
+				continue
+			}
+			if !seenNodes[nodeID] {
+				seenNodes[nodeID] = true
+				visitNode(node)
+			}
+		}
+		for _, link := range g.Links {
+			if owned[link.From] {
+				visitLink(link)
+				continue
+			}
+			key := graph.LinkKey{From: link.From, To: link.To, Kind: link.Kind}
+			if seenNodes[link.From] && !seenLinks[key] {
+				seenLinks[key] = true
+				visitLink(link)
+			}
+		}
+	}
+	return nil
+}
+
+// walkedImports returns the packages among walked that pkgs import, other
+// than pkgs themselves. Only their names and types are set.
+func walkedImports(pkgs []*packages.Package, walked map[string]bool) []*packages.Package {
+	if walked == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		seen[pkg.PkgPath] = true
+	}
+	var imports []*packages.Package
+	for _, pkg := range pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+		for _, imp := range pkg.Types.Imports() {
+			if !walked[imp.Path()] || seen[imp.Path()] {
+				continue
+			}
+			seen[imp.Path()] = true
+			imports = append(imports, &packages.Package{
+				ID:      imp.Path(),
+				Name:    imp.Name(),
+				PkgPath: imp.Path(),
+				Types:   imp,
+				Fset:    pkg.Fset,
+			})
+		}
+	}
+	return imports
+}
+
+// wholeGraphOptions returns which of the options working on the whole graph
+// are set in opts, by name
+func (opts *Options) wholeGraphOptions() map[string]bool {
+	return map[string]bool{
+		"UnsafeOnly": opts.UnsafeOnly,
+		"TestedBy":   opts.TestedBy,
+		"Condense":   opts.Condense,
+		"Reduce":     opts.Reduce,
+		"Centrality": opts.Centrality,
+		"ShortIDs":   opts.ShortIDs,
+		"Focus":      len(opts.Focus) > 0,
+		"MaxNodes":   opts.MaxNodes > 0,
+		"TopLinks":   opts.TopLinks > 0,
+	}
+}
+
+// unsupported returns an error naming the options that are set, or nil if
+// none is
+func unsupported(what string, options map[string]bool) error {
+	var names []string
+	for name, set := range options {
+		if set {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	slices.Sort(names)
+	return fmt.Errorf("%s does not support %s", what, strings.Join(names, ", "))
+}
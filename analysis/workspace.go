@@ -0,0 +1,57 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/packages"
+)
+
+// workspacePatterns returns package patterns matching all packages of the
+// modules used by the go.work file active in dir. The go command does not
+// expand ./... across modules, so each module directory gets its own pattern.
+func workspacePatterns(dir string) ([]string, error) {
+	cmd := exec.Command("go", "env", "GOWORK")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate go.work: %w", err)
+	}
+	workFile := strings.TrimSpace(string(out))
+	if workFile == "" || workFile == "off" {
+		return nil, fmt.Errorf("no go.work file found")
+	}
+
+	data, err := os.ReadFile(workFile)
+	if err != nil {
+		return nil, err
+	}
+	work, err := modfile.ParseWork(workFile, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, use := range work.Use {
+		dir := use.Path
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(workFile), dir)
+		}
+		patterns = append(patterns, dir+"/...")
+	}
+	return patterns, nil
+}
+
+// modulePath returns the path of the module containing pkg, if any
+func modulePath(pkg *packages.Package) string {
+	if pkg.Module == nil {
+		return ""
+	}
+	return pkg.Module.Path
+}
@@ -0,0 +1,209 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// communitiesCommand clusters the declarations by their links and reports the
+// clusters spanning several packages and the packages spanning several
+// clusters, which suggest where code belongs together or could be split.
+func communitiesCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("communities", flag.ExitOnError)
+	minSize := fs.Int("min-size", 3, "Minimum number of declarations of a cluster or package part to report")
+	resolution := fs.Float64("resolution", 1, "Louvain resolution, higher values produce smaller clusters")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope communities [-min-size 3] [-resolution 1] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		decls := g.DeclarationGraph()
+		communities := findCommunities(decls, *resolution)
+
+		// Group the members of every community by package
+		byPkg := make([]map[string][]string, len(communities))
+		pkgCommunities := make(map[string][]int)
+		for i, community := range communities {
+			byPkg[i] = make(map[string][]string)
+			for _, nodeID := range community {
+				node := decls.Nodes[nodeID]
+				if node.External {
+					continue
+				}
+				byPkg[i][node.Pkg] = append(byPkg[i][node.Pkg], nodeID)
+			}
+			for pkg, members := range byPkg[i] {
+				if len(members) >= *minSize {
+					pkgCommunities[pkg] = append(pkgCommunities[pkg], i)
+				}
+			}
+		}
+
+		fmt.Println("Clusters spanning packages:")
+		for i := range communities {
+			var pkgs []string
+			for pkg, members := range byPkg[i] {
+				if len(members) >= *minSize {
+					pkgs = append(pkgs, pkg)
+				}
+			}
+			if len(pkgs) < 2 {
+				continue
+			}
+			slices.Sort(pkgs)
+			fmt.Printf("\tcluster %d:\n", i+1)
+			for _, pkg := range pkgs {
+				fmt.Printf("\t\t%s: %s\n", pkg, strings.Join(byPkg[i][pkg], ", "))
+			}
+		}
+
+		fmt.Println("Packages spanning clusters:")
+		for _, pkg := range slices.Sorted(maps.Keys(pkgCommunities)) {
+			if len(pkgCommunities[pkg]) < 2 {
+				continue
+			}
+			fmt.Printf("\t%s:\n", pkg)
+			for _, i := range pkgCommunities[pkg] {
+				fmt.Printf("\t\tcluster %d: %s\n", i+1, strings.Join(byPkg[i][pkg], ", "))
+			}
+		}
+		return nil
+	}
+}
+
+// findCommunities partitions the nodes of g into clusters of densely linked
+// nodes using the Louvain method on the undirected, weighted graph. The
+// clusters are sorted by size and their members by ID.
+func findCommunities(g *graph.Graph, resolution float64) [][]string {
+	ids := slices.Sorted(maps.Keys(g.Nodes))
+	index := make(map[string]int, len(ids))
+	for i, nodeID := range ids {
+		index[nodeID] = i
+	}
+	adj := make([]map[int]float64, len(ids))
+	for i := range adj {
+		adj[i] = make(map[int]float64)
+	}
+	for _, link := range g.Links {
+		i, ok1 := index[link.From]
+		j, ok2 := index[link.To]
+		if !ok1 || !ok2 || i == j {
+			continue
+		}
+		adj[i][j] += float64(link.Weight)
+		adj[j][i] += float64(link.Weight)
+	}
+
+	// membership maps the original nodes to their community, which are
+	// the nodes of the current aggregated graph
+	membership := make([]int, len(ids))
+	for i := range membership {
+		membership[i] = i
+	}
+	for {
+		community, moved := louvainMove(adj, resolution)
+		if !moved {
+			break
+		}
+		// Renumber the communities and aggregate them into nodes
+		renumber := make(map[int]int)
+		for _, c := range community {
+			if _, ok := renumber[c]; !ok {
+				renumber[c] = len(renumber)
+			}
+		}
+		next := make([]map[int]float64, len(renumber))
+		for i := range next {
+			next[i] = make(map[int]float64)
+		}
+		for i, neighbors := range adj {
+			for j, w := range neighbors {
+				next[renumber[community[i]]][renumber[community[j]]] += w
+			}
+		}
+		for i := range membership {
+			membership[i] = renumber[community[membership[i]]]
+		}
+		adj = next
+	}
+
+	clusters := make(map[int][]string)
+	for i, c := range membership {
+		clusters[c] = append(clusters[c], ids[i])
+	}
+	result := slices.Collect(maps.Values(clusters))
+	slices.SortFunc(result, func(a, b []string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), cmp.Compare(a[0], b[0]))
+	})
+	return result
+}
+
+// louvainMove runs the local moving phase of the Louvain method: nodes are
+// moved to the neighboring community with the highest modularity gain until
+// no move improves the modularity. Self loops in adj are the internal weight
+// of aggregated nodes. It reports whether any node was moved.
+func louvainMove(adj []map[int]float64, resolution float64) (community []int, moved bool) {
+	n := len(adj)
+	community = make([]int, n)
+	degree := make([]float64, n)
+	total := make([]float64, n)
+	m2 := 0.0
+	for i, neighbors := range adj {
+		community[i] = i
+		for _, w := range neighbors {
+			degree[i] += w
+		}
+		total[i] = degree[i]
+		m2 += degree[i]
+	}
+	if m2 == 0 {
+		return community, false
+	}
+
+	for improved := true; improved; {
+		improved = false
+		for i := range n {
+			// Weights from i to the neighboring communities
+			weights := make(map[int]float64)
+			for j, w := range adj[i] {
+				if j != i {
+					weights[community[j]] += w
+				}
+			}
+			own := community[i]
+			total[own] -= degree[i]
+			best, bestGain := own, weights[own]-resolution*total[own]*degree[i]/m2
+			for _, c := range slices.Sorted(maps.Keys(weights)) {
+				if gain := weights[c] - resolution*total[c]*degree[i]/m2; gain > bestGain {
+					best, bestGain = c, gain
+				}
+			}
+			total[best] += degree[i]
+			if best != own {
+				community[i] = best
+				improved, moved = true, true
+			}
+		}
+	}
+	return community, moved
+}
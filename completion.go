@@ -0,0 +1,296 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+)
+
+// completionScripts are the completion scripts of the supported shells. They
+// call sgope __complete with the words of the command line up to the cursor
+// and fall back to file names if it prints nothing.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for sgope, load with: source <(sgope completion bash)
+_sgope() {
+	local IFS=$'\n'
+	COMPREPLY=($(sgope __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+	if [[ $COMP_CWORD -gt 1 ]]; then
+		# quote symbol IDs and keep directories open
+		compopt -o filenames
+	fi
+}
+complete -o default -F _sgope sgope
+`,
+	"zsh": `#compdef sgope
+# zsh completion for sgope, load with: source <(sgope completion zsh)
+_sgope() {
+	local -a candidates
+	candidates=(${(f)"$(sgope __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+	if (( ${#candidates} == 0 )); then
+		_files
+		return
+	fi
+	compadd -S '' -- ${(M)candidates:#*/}
+	compadd -- ${candidates:#*/}
+}
+if [[ $funcstack[1] == _sgope ]]; then
+	_sgope "$@"
+else
+	compdef _sgope sgope
+fi
+`,
+	"fish": `# fish completion for sgope, load with: sgope completion fish | source
+function __sgope_complete
+	set -l args (commandline -opc)
+	set -e args[1]
+	sgope __complete $args (commandline -ct) 2>/dev/null
+end
+complete -c sgope -f -a '(__sgope_complete)'
+complete -c sgope -n 'not count (__sgope_complete) >/dev/null' -F
+`,
+}
+
+// symbolArgs is the number of leading arguments of commands that are symbols
+var symbolArgs = map[string]int{
+	"query":     1,
+	"tests-for": 1,
+	"why":       2,
+}
+
+// completionCommand prints the completion script of a shell
+func completionCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope completion bash|zsh|fish")
+		fmt.Fprintln(fs.Output(), "  Completes commands, flags, package paths and the symbol IDs of the current module")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		script, ok := completionScripts[fs.Arg(0)]
+		if !ok {
+			return fmt.Errorf("unknown shell %q (available: %s)", fs.Arg(0), strings.Join(slices.Sorted(maps.Keys(completionScripts)), ", "))
+		}
+		_, err := fmt.Print(script)
+		return err
+	}
+}
+
+// runComplete prints the candidates for the last of the given words of a
+// command line, one per line. It prints nothing to let the shell complete
+// file names.
+func runComplete(words []string) {
+	if len(words) == 0 {
+		return
+	}
+	cur := words[len(words)-1]
+	words = words[:len(words)-1]
+	for _, candidate := range complete(words, cur) {
+		if strings.HasPrefix(candidate, cur) {
+			fmt.Println(candidate)
+		}
+	}
+}
+
+// complete returns the candidates for the word cur following the given words
+func complete(words []string, cur string) []string {
+	if len(words) == 0 {
+		return append(slices.Sorted(maps.Keys(commands)), "help")
+	}
+	name := words[0]
+	switch {
+	case name == "help" && len(words) == 1:
+		return slices.Sorted(maps.Keys(commands))
+	case name == "completion" && len(words) == 1:
+		return slices.Sorted(maps.Keys(completionScripts))
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		return nil
+	}
+	fs, _ := cmd.flags()
+
+	// Find the flag whose value cur is, or the position of cur among the
+	// arguments
+	var valueOf *flag.Flag
+	position := 0
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		if word == "--" || !strings.HasPrefix(word, "-") {
+			position++
+			continue
+		}
+		flagName, _, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		f := fs.Lookup(flagName)
+		if f == nil || hasValue || isBoolFlag(f) {
+			continue
+		}
+		if i == len(words)-1 {
+			valueOf = f
+		} else {
+			i++
+		}
+	}
+
+	if valueOf == nil && strings.HasPrefix(cur, "-") {
+		flagName, value, hasValue := strings.Cut(strings.TrimLeft(cur, "-"), "=")
+		if !hasValue {
+			var candidates []string
+			fs.VisitAll(func(f *flag.Flag) {
+				candidates = append(candidates, "-"+f.Name)
+			})
+			return candidates
+		}
+		if f := fs.Lookup(flagName); f != nil {
+			prefix := cur[:len(cur)-len(value)]
+			var candidates []string
+			for _, candidate := range completeValue(f, value) {
+				candidates = append(candidates, prefix+candidate)
+			}
+			return candidates
+		}
+		return nil
+	}
+	if valueOf != nil {
+		return completeValue(valueOf, cur)
+	}
+	if position < symbolArgs[name] {
+		return symbolIDs()
+	}
+	if fs.Lookup("tags") != nil {
+		return packagePaths(cur)
+	}
+	return nil
+}
+
+// completeValue returns the candidates for the value of a flag, which are the
+// symbol IDs for flags taking an ID
+func completeValue(f *flag.Flag, cur string) []string {
+	if argName, _ := flag.UnquoteUsage(f); argName == "ID" {
+		return symbolIDs()
+	}
+	return nil
+}
+
+// isBoolFlag reports whether a flag can be given without a value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// packagePaths returns the package path patterns of the directories below
+// the directory of cur
+func packagePaths(cur string) []string {
+	switch cur {
+	case "", ".":
+		cur = "./"
+	case "..":
+		cur = "../"
+	}
+	if !strings.HasPrefix(cur, "./") && !strings.HasPrefix(cur, "../") && !path.IsAbs(cur) {
+		return nil
+	}
+	dir, _ := path.Split(cur)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	candidates := []string{dir + "..."}
+	for _, entry := range entries {
+		if entry.IsDir() && !ignoredDir(entry.Name()) {
+			candidates = append(candidates, dir+entry.Name()+"/", dir+entry.Name()+"/...")
+		}
+	}
+	return candidates
+}
+
+// ignoredDir reports whether the go command ignores a directory in ./...
+// patterns
+func ignoredDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata"
+}
+
+// symbolIDs returns the node IDs of the module containing the current
+// directory. They are cached until a file of the module changes, analyzing
+// the module only when completing a symbol the first time after a change.
+func symbolIDs() []string {
+	root, err := moduleRoot()
+	if root == "" || err != nil {
+		return nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	hash := sha256.Sum256([]byte(root))
+	cacheFile := filepath.Join(cacheDir, "sgope", "ids-"+hex.EncodeToString(hash[:8]))
+	if info, err := os.Stat(cacheFile); err == nil && !modifiedSince(root, info.ModTime().Unix()) {
+		if data, err := os.ReadFile(cacheFile); err == nil {
+			return strings.FieldsFunc(string(data), func(r rune) bool { return r == '\n' })
+		}
+	}
+
+	// Analyze with the flags of the project configuration, so the IDs match
+	// those of other commands
+	fs := flag.NewFlagSet("complete", flag.ContinueOnError)
+	opts := addAnalyzeFlags(fs)
+	if config, _ := loadConfig(); config != nil {
+		config.apply(fs)
+	}
+	opts.Progress, opts.Focus, opts.ShortIDs, opts.Strict = nil, nil, false, false
+	g, err := analysis.Analyze(opts, filepath.Join(root, "..."))
+	if err != nil {
+		return nil
+	}
+	ids := slices.Sorted(maps.Keys(g.Nodes))
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0o755); err == nil {
+		os.WriteFile(cacheFile, []byte(strings.Join(ids, "\n")+"\n"), 0o644)
+	}
+	return ids
+}
+
+// modifiedSince reports whether a Go source file, go.mod, go.sum or the
+// project configuration below root was modified after the given Unix time
+func modifiedSince(root string, unix int64) bool {
+	modified := false
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path != root && ignoredDir(name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" && name != configFile {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Unix() >= unix {
+			modified = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return modified
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/phyrog/sgope/analysis"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the name of the project configuration file in the module
+// root
+const configFile = ".sgope.yaml"
+
+// projectConfig is the content of a project configuration file with the
+// defaults of a team
+type projectConfig struct {
+	// Flags sets flags of every command that has them
+	Flags map[string]any `yaml:"flags"`
+	// Commands sets flags of single commands, taking precedence over Flags
+	Commands map[string]map[string]any `yaml:"commands"`
+	// Groups are classification rules as in -classify files
+	Groups []analysis.ClassifyRule `yaml:"groups"`
+	// Lint holds the rules of sgope lint as in -rules files
+	Lint *ruleSet `yaml:"lint"`
+
+	path string
+}
+
+// loadConfig reads the configuration file from the root of the module
+// containing the current directory, if there is one
+var loadConfig = sync.OnceValues(func() (*projectConfig, error) {
+	dir, err := moduleRoot()
+	if dir == "" || err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, configFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	config := &projectConfig{path: path}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if config.Lint != nil {
+		if err := config.Lint.init(path); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+})
+
+// moduleRoot returns the directory of the go.mod file of the module
+// containing the current directory, or "" outside of modules
+func moduleRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// parseFlags parses the flags of a command like fs.Parse, after setting the
+// defaults of the project configuration. Flags given on the command line
+// take precedence, and repeatable flags add to the configured values.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	config, err := loadConfig()
+	if err == nil && config != nil {
+		err = config.apply(fs)
+	}
+	if err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		os.Exit(2)
+	}
+	fs.Parse(args)
+}
+
+// apply sets the flags of fs configured in c
+func (c *projectConfig) apply(fs *flag.FlagSet) error {
+	for name, value := range c.Flags {
+		if fs.Lookup(name) == nil {
+			continue
+		}
+		if err := c.set(fs, name, value); err != nil {
+			return err
+		}
+	}
+	for name, value := range c.Commands[fs.Name()] {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: command %s has no flag -%s", c.path, fs.Name(), name)
+		}
+		if err := c.set(fs, name, value); err != nil {
+			return err
+		}
+	}
+	if f := fs.Lookup("classify"); f != nil && len(c.Groups) > 0 {
+		if err := f.Value.(*classifyFlag).add(c.path, c.Groups); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set sets a flag to a configured value, which is a list for repeatable
+// flags. Relative file names are resolved against the directory of the
+// configuration file.
+func (c *projectConfig) set(fs *flag.FlagSet, name string, value any) error {
+	values, ok := value.([]any)
+	if !ok {
+		values = []any{value}
+	}
+	argName, _ := flag.UnquoteUsage(fs.Lookup(name))
+	for _, v := range values {
+		s := fmt.Sprint(v)
+		if argName == "file" && s != "-" && !filepath.IsAbs(s) {
+			s = filepath.Join(filepath.Dir(c.path), s)
+		}
+		if err := fs.Set(name, s); err != nil {
+			return fmt.Errorf("%s: flag -%s: %w", c.path, name, err)
+		}
+	}
+	return nil
+}
+
+// lintRules returns the configured rules of sgope lint, or nil
+func (c *projectConfig) lintRules() *ruleSet {
+	if c == nil {
+		return nil
+	}
+	return c.Lint
+}
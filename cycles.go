@@ -0,0 +1,98 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// Granularities of the cycle report
+const (
+	levelSymbol  = "symbol"
+	levelPackage = "package"
+)
+
+// cyclesCommand reports the dependency cycles between symbols or packages, as
+// a list or as a graph containing only the nodes and links of the cycles.
+func cyclesCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("cycles", flag.ExitOnError)
+	level := fs.String("level", levelSymbol, "Granularity of the cycles ("+levelSymbol+", "+levelPackage+")")
+	jsonMode := fs.Bool("json", false, "Output the cycles as JSON findings")
+	graphMode := fs.Bool("graph", false, "Output the cycles as graph JSON, with nodes numbered by their cycle")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope cycles [-level symbol|package] [-json] [-graph] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if *level != levelSymbol && *level != levelPackage {
+			return fmt.Errorf("unknown level %q (available: %s, %s)", *level, levelSymbol, levelPackage)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		if *level == levelPackage {
+			g = g.PackageGraph()
+		} else {
+			g = g.OwnerGraph()
+		}
+		cycles := g.Cycles()
+
+		if *graphMode {
+			// Mark the nodes with the 1-based number of their cycle
+			sub := g.CycleGraph(cycles)
+			for i, cycle := range cycles {
+				for _, nodeID := range cycle {
+					sub.Nodes[nodeID] = sub.Nodes[nodeID].WithAttribute("cycle", i+1)
+				}
+			}
+			jsonData, err := json.Marshal(sub)
+			if err != nil {
+				return fmt.Errorf("JSON marshaling error: %w", err)
+			}
+			if err := render.WriteJSON(os.Stdout, jsonData); err != nil {
+				return err
+			}
+		}
+
+		// Print a shortest cycle through the first member of every component,
+		// followed by the members not on that cycle
+		succ := g.Successors()
+		var findings []finding
+		for i, cycle := range cycles {
+			path := graph.CyclePath(succ, cycle)
+			message := fmt.Sprintf("cycle of %d %ss: %s", len(cycle), *level, strings.Join(append(path, path[0]), " -> "))
+			first := g.Nodes[cycle[0]]
+			findings = append(findings, finding{Check: "cycles", Message: message, Node: first.Id, Position: first.Position, Related: cycle[1:]})
+			if *jsonMode || *graphMode {
+				continue
+			}
+			fmt.Printf("cycle %d (%d %ss):\n", i+1, len(cycle), *level)
+			fmt.Printf("\t%s\n", strings.Join(append(path, path[0]), " -> "))
+			for _, nodeID := range cycle {
+				if !slices.Contains(path, nodeID) {
+					fmt.Printf("\talso %s\n", nodeID)
+				}
+			}
+		}
+		if len(cycles) == 0 && !*jsonMode && !*graphMode {
+			fmt.Fprintln(os.Stderr, "No cycles found")
+		}
+		return reportFindings(os.Stdout, "cycles", exitCycles, findings, *jsonMode && !*graphMode)
+	}
+}
@@ -0,0 +1,291 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phyrog/sgope/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// maxCachedLoads bounds the number of package sets a daemon keeps loaded
+const maxCachedLoads = 4
+
+// daemonRejectedFlags are the analysis flags requests cannot set, because
+// they run commands or read files on behalf of the client
+var daemonRejectedFlags = []string{"plugin", "vulns", "rev", "changed-since", "overlay", "classify"}
+
+// daemonCommand keeps loaded packages in memory and answers analysis requests
+// over HTTP on a unix socket, so repeated analyses of the same packages only
+// pay for loading them again after files changed.
+func daemonCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	socket := fs.String("socket", filepath.Join(os.TempDir(), "sgope.sock"), "Unix `socket` to listen on")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope daemon [-socket path]")
+		fmt.Fprintln(fs.Output(), "  POST a JSON request {\"dir\": ..., \"args\": [...]} to /analyze for the graph")
+		fmt.Fprintln(fs.Output(), "  of the analysis flags and package paths in args, resolved in dir")
+		fmt.Fprintln(fs.Output(), "  The flags -plugin, -vulns, -rev, -changed-since, -overlay and -classify are rejected.")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if conn, err := net.Dial("unix", *socket); err == nil {
+			conn.Close()
+			return fmt.Errorf("a daemon is already listening on %s", *socket)
+		}
+		l, err := listenUnix(*socket)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(*socket)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		server := &http.Server{Handler: daemonHandler(&loadCache{})}
+		go func() {
+			<-ctx.Done()
+			server.Shutdown(context.Background())
+		}()
+
+		fmt.Fprintf(os.Stderr, "Listening on %s\n", *socket)
+		if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// listenUnix listens on a unix socket at path that only the current user can
+// connect to. The socket is created in a private directory and moved to path
+// once its permissions are set, replacing a socket left behind by a daemon
+// that did not shut down cleanly, but no other file.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket == 0 {
+		return nil, fmt.Errorf("%s exists and is not a socket", path)
+	}
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".sgope-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "s")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// The socket is removed from path instead of tmp once the daemon stops
+	l.SetUnlinkOnClose(false)
+	if err = os.Chmod(tmp, 0o600); err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// rejectedFlag is a flag.Value failing to set a flag daemon requests cannot
+// set
+type rejectedFlag struct{ flag.Value }
+
+func (rejectedFlag) Set(string) error {
+	return errors.New("not allowed in daemon requests")
+}
+
+// daemonRequest is the body of a request to the /analyze endpoint of the
+// daemon
+type daemonRequest struct {
+	// Dir is the directory package paths are resolved in
+	Dir string `json:"dir"`
+	// Args are the analysis flags and package paths, as on the command line
+	Args []string `json:"args"`
+}
+
+// daemonHandler serves the /analyze endpoint, which responds with the graph
+// of a daemonRequest. The focus and depth query parameters restrict the
+// graph to the neighborhood of a node, like -focus and -depth.
+func daemonHandler(cache *loadCache) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /analyze", func(w http.ResponseWriter, r *http.Request) {
+		var req daemonRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "malformed request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		opts := addAnalyzeFlags(fs)
+		// Reject the flags while parsing, before flags like -classify read
+		// their files
+		for _, name := range daemonRejectedFlags {
+			fs.Lookup(name).Value = rejectedFlag{fs.Lookup(name).Value}
+		}
+		if err := fs.Parse(req.Args); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Dir = req.Dir
+		opts.Load = cache.load
+		if focus := r.URL.Query().Get("focus"); focus != "" {
+			opts.Focus = []string{focus}
+		}
+		if depth := r.URL.Query().Get("depth"); depth != "" {
+			var err error
+			if opts.FocusDepth, err = strconv.Atoi(depth); err != nil {
+				http.Error(w, fmt.Sprintf("invalid depth %q", depth), http.StatusBadRequest)
+				return
+			}
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g)
+	})
+	return mux
+}
+
+// loadCache keeps loaded packages by their configuration and patterns until
+// the files of the main modules change
+type loadCache struct {
+	mu      sync.Mutex
+	entries map[string]*loadEntry
+}
+
+// loadEntry is a set of loaded packages
+type loadEntry struct {
+	pkgs []*packages.Package
+	// modTimes holds the modification times of the files and directories
+	// of the main modules the packages were loaded from
+	modTimes map[string]time.Time
+	lastUsed time.Time
+}
+
+// load returns the packages loaded for cfg and patterns, loading them if they
+// are not cached or their files changed since. The returned slice belongs to
+// the caller, but the packages are shared by all loads of the same packages
+// and must not be modified.
+func (c *loadCache) load(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+	key := fmt.Sprintf("%q %q %q %v %d %q %x", cfg.Dir, cfg.Env, cfg.BuildFlags, cfg.Tests, cfg.Mode, patterns, overlayHash(cfg.Overlay))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && !entry.changed() {
+		entry.lastUsed = time.Now()
+		return slices.Clone(entry.pkgs), nil
+	}
+
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*loadEntry)
+	}
+	if len(c.entries) >= maxCachedLoads {
+		c.evict()
+	}
+	c.entries[key] = &loadEntry{pkgs: pkgs, modTimes: modTimes(pkgs), lastUsed: time.Now()}
+	return slices.Clone(pkgs), nil
+}
+
+// overlayHash returns a hash of the file paths and contents of an overlay
+func overlayHash(overlay map[string][]byte) []byte {
+	h := sha256.New()
+	for _, file := range slices.Sorted(maps.Keys(overlay)) {
+		fmt.Fprintf(h, "%q %d\n", file, len(overlay[file]))
+		h.Write(overlay[file])
+	}
+	return h.Sum(nil)
+}
+
+// changed reports whether the files of any of the cached packages changed.
+// It drops the changed entries, so a change is reported once even if the
+// packages of an entry are not loaded again.
+func (c *loadCache) changed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changed := false
+	for key, entry := range c.entries {
+		if entry.changed() {
+			delete(c.entries, key)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// evict drops the least recently used entry
+func (c *loadCache) evict() {
+	var oldest string
+	for key, entry := range c.entries {
+		if oldest == "" || entry.lastUsed.Before(c.entries[oldest].lastUsed) {
+			oldest = key
+		}
+	}
+	delete(c.entries, oldest)
+}
+
+// modTimes returns the modification times of the source files of the
+// packages of the main modules, their directories, which change when files
+// are added or removed, and the module files
+func modTimes(pkgs []*packages.Package) map[string]time.Time {
+	times := make(map[string]time.Time)
+	add := func(path string) {
+		if _, ok := times[path]; ok {
+			return
+		}
+		if info, err := os.Stat(path); err == nil {
+			times[path] = info.ModTime()
+		}
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Module == nil || !pkg.Module.Main {
+			return
+		}
+		if pkg.Module.GoMod != "" {
+			add(pkg.Module.GoMod)
+			add(strings.TrimSuffix(pkg.Module.GoMod, ".mod") + ".sum")
+		}
+		for _, files := range [][]string{pkg.GoFiles, pkg.OtherFiles, pkg.IgnoredFiles} {
+			for _, file := range files {
+				add(file)
+				add(filepath.Dir(file))
+			}
+		}
+	})
+	return times
+}
+
+// changed reports whether any of the files of the entry changed
+func (e *loadEntry) changed() bool {
+	for path, modTime := range e.modTimes {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
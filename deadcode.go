@@ -0,0 +1,166 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// Reachability roots selectable with deadcode -roots
+const (
+	rootMain     = "main"     // main functions of main packages
+	rootExported = "exported" // the public API of the analyzed packages
+	rootTests    = "tests"    // test, benchmark, fuzz and example functions
+)
+
+var deadcodeRoots = []string{rootMain, rootExported, rootTests}
+
+// deadcodeCommand lists the declarations that cannot be reached from the given
+// roots over the links of the graph.
+func deadcodeCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("deadcode", flag.ExitOnError)
+	roots := fs.String("roots", strings.Join(deadcodeRoots, ","), "Comma-separated reachability roots ("+strings.Join(deadcodeRoots, ", ")+")")
+	jsonMode := fs.Bool("json", false, "Output the unreachable declarations as JSON findings")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope deadcode [-roots main,exported,tests] [-json] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		var rootSet []string
+		for root := range strings.SplitSeq(*roots, ",") {
+			if root = strings.TrimSpace(root); root == "" {
+				continue
+			}
+			if !slices.Contains(deadcodeRoots, root) {
+				return fmt.Errorf("unknown root %q (available: %s)", root, strings.Join(deadcodeRoots, ", "))
+			}
+			rootSet = append(rootSet, root)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+
+		var findings []finding
+		for _, node := range deadCode(g, rootSet) {
+			message := fmt.Sprintf("unreachable %s %s", nodeKind(node), node.Id)
+			if !*jsonMode {
+				fmt.Printf("%s: %s\n", nodePosition(node), message)
+			}
+			findings = append(findings, finding{Check: "deadcode", Message: message, Node: node.Id, Position: node.Position})
+		}
+		return reportFindings(os.Stdout, "deadcode", exitDeadCode, findings, *jsonMode)
+	}
+}
+
+// nodePosition formats the start of a node's source range as file:line:col
+func nodePosition(node *graph.Node) string {
+	if node.Position == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%s:%d:%d", node.Position.File, node.Position.StartLine, node.Position.StartCol)
+}
+
+// nodeKind describes the kind of a node for reports, telling methods apart
+// from functions
+func nodeKind(node *graph.Node) string {
+	if node.Type == graph.FuncMethod {
+		return graph.FuncMethod
+	}
+	return node.Kind
+}
+
+// deadCode returns the functions, methods, types, variables and constants
+// that are not reachable from roots, sorted by position. Declarations in test
+// files are only reported if tests are a root.
+func deadCode(g *graph.Graph, roots []string) []*graph.Node {
+	var rootIDs []string
+	for _, node := range g.Nodes {
+		if isDeadcodeRoot(node, roots) {
+			rootIDs = append(rootIDs, node.Id)
+		}
+	}
+	reachable := g.Reachability().From(rootIDs)
+
+	var dead []*graph.Node
+	for _, node := range g.Nodes {
+		if reachable[node.Id] || !isDeadcodeCandidate(g, node, roots) {
+			continue
+		}
+		dead = append(dead, node)
+	}
+	graph.SortByPosition(dead)
+	return dead
+}
+
+// isDeadcodeRoot reports whether node is always reachable given roots. Init
+// functions run whenever their package is linked and blank declarations like
+// var _ I = T{} are compile-time assertions, so both are always roots.
+func isDeadcodeRoot(node *graph.Node, roots []string) bool {
+	if node.LocalName == "_" {
+		return true
+	}
+	if node.Kind == graph.KindFunc && node.Type == graph.FuncBasic && node.Parent == "" && strings.HasPrefix(node.LocalName, "init#") {
+		return true
+	}
+	for _, root := range roots {
+		switch root {
+		case rootMain:
+			if node.Kind == graph.KindFunc && node.Type == graph.FuncBasic && node.Parent == "" &&
+				node.LocalName == "main" && node.Package != nil && node.Package.Name == "main" {
+				return true
+			}
+		case rootExported:
+			if node.Exported {
+				return true
+			}
+		case rootTests:
+			if graph.IsTestNode(node) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDeadcodeCandidate reports whether node is a declaration that deadcode
+// reports if unreachable. Packages, files, const groups, fields, closures,
+// instances and interface methods are only reachable through other nodes,
+// and external nodes are not part of the analyzed code.
+func isDeadcodeCandidate(g *graph.Graph, node *graph.Node, roots []string) bool {
+	if node.External || node.Kind == graph.KindPackage || node.Kind == graph.KindFile {
+		return false
+	}
+	if node.Test && !slices.Contains(roots, rootTests) {
+		return false
+	}
+	switch node.Type {
+	case graph.VarField, graph.FuncClosure, graph.ConstGroup:
+		return false
+	case graph.FuncMethod:
+		if parent, ok := g.Nodes[node.Parent]; ok && parent.Type == graph.TypeInterface {
+			return false
+		}
+	}
+	// Instances are children of their generic origin
+	if node.Type != graph.FuncMethod && node.Parent != "" {
+		if parent, ok := g.Nodes[node.Parent]; ok && parent.Type != graph.ConstGroup {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,264 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// Diff states of the nodes and links of a diff graph
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+)
+
+// graphDiff is the difference between two graphs
+type graphDiff struct {
+	AddedNodes   []*graph.Node `json:"addedNodes"`
+	RemovedNodes []*graph.Node `json:"removedNodes"`
+	AddedLinks   []graph.Link  `json:"addedLinks"`
+	RemovedLinks []graph.Link  `json:"removedLinks"`
+	// NewCycles lists the dependency cycles of the new graph whose members
+	// did not form a cycle in the old graph
+	NewCycles    [][]string    `json:"newCycles"`
+	FanInChanges []fanInChange `json:"fanInChanges"`
+}
+
+// fanInChange is the change of the fan-in of a node present in both graphs
+type fanInChange struct {
+	Node string `json:"node"`
+	Old  int    `json:"old"`
+	New  int    `json:"new"`
+}
+
+// diffCommand compares two graph files and reports the added and removed nodes
+// and links, new dependency cycles and fan-in changes, to review the
+// architectural impact of a change.
+func diffCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "Output format (text, json, html)")
+	output := fs.String("o", "-", "Output file, '-' for stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope diff [-format text|json|html] [-o file] <old.json> <new.json>")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() != 2 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if *format != "text" && *format != "json" && *format != "html" {
+			return fmt.Errorf("unknown format %q (available: text, json, html)", *format)
+		}
+
+		oldGraph, err := readGraph(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		newGraph, err := readGraph(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		diff := diffGraphs(oldGraph, newGraph)
+
+		var jsonData []byte
+		switch *format {
+		case "text":
+			w := io.Writer(os.Stdout)
+			if *output != "" && *output != "-" {
+				f, err := os.Create(*output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			diff.write(w)
+			return nil
+		case "json":
+			jsonData, err = json.Marshal(diff)
+		case "html":
+			jsonData, err = json.Marshal(diff.graph(newGraph))
+		}
+		if err != nil {
+			return fmt.Errorf("JSON marshaling error: %w", err)
+		}
+		return render.WriteOutput(*output, *format, jsonData)
+	}
+}
+
+// readGraph reads a graph written with -format json. Shortened IDs are
+// expanded to full IDs, since short IDs are not stable between runs.
+func readGraph(path string) (*graph.Graph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readGraphFrom(path, f)
+}
+
+// readGraphFrom reads a graph from r, named name in errors
+func readGraphFrom(name string, r io.Reader) (*graph.Graph, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var g graph.Graph
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("%s: malformed graph: %w", name, err)
+	}
+	g.ExpandIDs()
+	return &g, nil
+}
+
+// diffGraphs computes the difference from oldGraph to newGraph. Links are
+// compared by their endpoints and kind, ignoring weight changes.
+func diffGraphs(oldGraph, newGraph *graph.Graph) *graphDiff {
+	diff := &graphDiff{}
+	for nodeID, node := range newGraph.Nodes {
+		if _, ok := oldGraph.Nodes[nodeID]; !ok {
+			diff.AddedNodes = append(diff.AddedNodes, node)
+		}
+	}
+	for nodeID, node := range oldGraph.Nodes {
+		if _, ok := newGraph.Nodes[nodeID]; !ok {
+			diff.RemovedNodes = append(diff.RemovedNodes, node)
+		}
+	}
+	byID := func(a, b *graph.Node) int { return cmp.Compare(a.Id, b.Id) }
+	slices.SortFunc(diff.AddedNodes, byID)
+	slices.SortFunc(diff.RemovedNodes, byID)
+
+	diff.AddedLinks = linksNotIn(newGraph.Links, oldGraph.Links)
+	diff.RemovedLinks = linksNotIn(oldGraph.Links, newGraph.Links)
+
+	oldCycles := make(map[string]bool)
+	for _, cycle := range oldGraph.OwnerGraph().Cycles() {
+		oldCycles[strings.Join(cycle, "\n")] = true
+	}
+	for _, cycle := range newGraph.OwnerGraph().Cycles() {
+		if !oldCycles[strings.Join(cycle, "\n")] {
+			diff.NewCycles = append(diff.NewCycles, cycle)
+		}
+	}
+
+	oldFan, newFan := oldGraph.FanCounts(), newGraph.FanCounts()
+	for nodeID := range newGraph.Nodes {
+		if _, ok := oldGraph.Nodes[nodeID]; !ok {
+			continue
+		}
+		if o, n := oldFan.In[nodeID], newFan.In[nodeID]; o != n {
+			diff.FanInChanges = append(diff.FanInChanges, fanInChange{nodeID, o, n})
+		}
+	}
+	// Largest changes first
+	slices.SortFunc(diff.FanInChanges, func(a, b fanInChange) int {
+		return cmp.Or(
+			cmp.Compare(abs(b.New-b.Old), abs(a.New-a.Old)),
+			cmp.Compare(a.Node, b.Node),
+		)
+	})
+	return diff
+}
+
+// linksNotIn returns the links of links whose endpoints and kind do not occur
+// in other, sorted by endpoints and kind
+func linksNotIn(links, other []graph.Link) []graph.Link {
+	seen := make(map[graph.LinkKey]bool, len(other))
+	for _, link := range other {
+		seen[graph.LinkKey{From: link.From, To: link.To, Kind: link.Kind}] = true
+	}
+	var missing []graph.Link
+	for _, link := range links {
+		if !seen[graph.LinkKey{From: link.From, To: link.To, Kind: link.Kind}] {
+			missing = append(missing, link)
+		}
+	}
+	slices.SortFunc(missing, func(a, b graph.Link) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To), cmp.Compare(a.Kind, b.Kind))
+	})
+	return missing
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// write prints the diff as text, one change per line
+func (d *graphDiff) write(w io.Writer) {
+	for _, node := range d.AddedNodes {
+		fmt.Fprintf(w, "+ %s %s\n", nodeKind(node), node.Id)
+	}
+	for _, node := range d.RemovedNodes {
+		fmt.Fprintf(w, "- %s %s\n", nodeKind(node), node.Id)
+	}
+	for _, link := range d.AddedLinks {
+		fmt.Fprintf(w, "+ %s -%s-> %s\n", link.From, linkKindName(link), link.To)
+	}
+	for _, link := range d.RemovedLinks {
+		fmt.Fprintf(w, "- %s -%s-> %s\n", link.From, linkKindName(link), link.To)
+	}
+	for _, cycle := range d.NewCycles {
+		fmt.Fprintf(w, "new cycle: %s\n", strings.Join(cycle, ", "))
+	}
+	for _, change := range d.FanInChanges {
+		fmt.Fprintf(w, "fan-in %s: %d -> %d (%+d)\n", change.Node, change.Old, change.New, change.New-change.Old)
+	}
+	fmt.Fprintf(w, "%d nodes added, %d removed; %d links added, %d removed; %d new cycles\n",
+		len(d.AddedNodes), len(d.RemovedNodes), len(d.AddedLinks), len(d.RemovedLinks), len(d.NewCycles))
+}
+
+// linkKindName returns the kind of a link, where links without a kind are
+// references
+func linkKindName(link graph.Link) string {
+	if link.Kind == "" {
+		return graph.LinkReference
+	}
+	return link.Kind
+}
+
+// graph returns newGraph together with the removed nodes and links, with all
+// added and removed nodes and links marked in their diff attribute, for
+// rendering the diff
+func (d *graphDiff) graph(newGraph *graph.Graph) *graph.Graph {
+	union := &graph.Graph{Nodes: make(map[string]*graph.Node, len(newGraph.Nodes)+len(d.RemovedNodes))}
+	for nodeID, node := range newGraph.Nodes {
+		union.Nodes[nodeID] = node
+	}
+	for _, node := range d.AddedNodes {
+		union.Nodes[node.Id] = node.WithAttribute("diff", diffAdded)
+	}
+	for _, node := range d.RemovedNodes {
+		union.Nodes[node.Id] = node.WithAttribute("diff", diffRemoved)
+	}
+
+	added := make(map[graph.LinkKey]bool, len(d.AddedLinks))
+	for _, link := range d.AddedLinks {
+		added[graph.LinkKey{From: link.From, To: link.To, Kind: link.Kind}] = true
+	}
+	for _, link := range newGraph.Links {
+		if added[graph.LinkKey{From: link.From, To: link.To, Kind: link.Kind}] {
+			link = link.WithAttribute("diff", diffAdded)
+		}
+		union.Links = append(union.Links, link)
+	}
+	for _, link := range d.RemovedLinks {
+		link = link.WithAttribute("diff", diffRemoved)
+		union.Links = append(union.Links, link)
+	}
+	return union
+}
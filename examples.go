@@ -0,0 +1,82 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// examplesCommand reports which exported symbols are documented by example
+// functions.
+func examplesCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("examples", flag.ExitOnError)
+	all := fs.Bool("all", false, "Also list the symbols that have examples")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope examples [-all] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+
+		documented, undocumented := exampleCoverage(g)
+		if *all {
+			for _, node := range documented {
+				fmt.Printf("%s: exported %s %s has examples\n", nodePosition(node), nodeKind(node), node.Id)
+			}
+		}
+		for _, node := range undocumented {
+			fmt.Printf("%s: exported %s %s has no example\n", nodePosition(node), nodeKind(node), node.Id)
+		}
+		fmt.Fprintf(os.Stderr, "%d of %d exported symbols have examples\n", len(documented), len(documented)+len(undocumented))
+		return nil
+	}
+}
+
+// exampleCoverage returns the exported functions, types and methods of the
+// analyzed code that are documented by an example and those that are not,
+// sorted by position.
+func exampleCoverage(g *graph.Graph) (documented, undocumented []*graph.Node) {
+	demonstrated := make(map[string]bool)
+	for _, link := range g.Links {
+		if link.Kind == graph.LinkDemonstrates {
+			demonstrated[link.To] = true
+		}
+	}
+	for _, node := range g.Nodes {
+		if !node.Exported || node.Test || node.External {
+			continue
+		}
+		switch {
+		case node.Kind == graph.KindFunc && (node.Type == graph.FuncBasic || node.Type == graph.FuncMethod):
+		case node.Kind == graph.KindType:
+		default:
+			continue
+		}
+		// Generic instances are documented by their origin
+		if node.Type != graph.FuncMethod && node.Parent != "" {
+			continue
+		}
+		if demonstrated[node.Id] {
+			documented = append(documented, node)
+		} else {
+			undocumented = append(undocumented, node)
+		}
+	}
+	graph.SortByPosition(documented)
+	graph.SortByPosition(undocumented)
+	return documented, undocumented
+}
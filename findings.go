@@ -0,0 +1,75 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// Exit codes of the checking subcommands. Failures to run a command exit
+// with 1 and usage errors with 2.
+const (
+	exitViolations = 3 // lint found architecture rule violations
+	exitCycles     = 4 // cycles found dependency cycles
+	exitDeadCode   = 5 // deadcode found unreachable declarations
+)
+
+// findingsNames describes the findings of every checking subcommand
+var findingsNames = map[string]string{
+	"lint":     "architecture rule violations",
+	"cycles":   "dependency cycles",
+	"deadcode": "unreachable declarations",
+}
+
+// finding is a problem reported by a checking subcommand
+type finding struct {
+	// Check is the subcommand that reported the finding
+	Check   string `json:"check"`
+	Message string `json:"message"`
+	// Node is the ID of the node the finding is about, if any
+	Node     string          `json:"node,omitempty"`
+	Position *graph.Position `json:"position,omitempty"`
+	// Related lists the IDs of further nodes involved, e.g. the target of a
+	// forbidden link or the members of a cycle
+	Related []string `json:"related,omitempty"`
+}
+
+// findingsError is returned by checking subcommands that found problems, so
+// the process exits with the command's exit code
+type findingsError struct {
+	check string
+	count int
+	code  int
+}
+
+func (e *findingsError) Error() string {
+	return fmt.Sprintf("%d %s", e.count, findingsNames[e.check])
+}
+
+// reportFindings writes findings as JSON to w if jsonMode is set, and
+// returns a findingsError with the given exit code if there are any
+func reportFindings(w io.Writer, check string, code int, findings []finding, jsonMode bool) error {
+	if jsonMode {
+		if findings == nil {
+			findings = []finding{}
+		}
+		jsonData, err := json.Marshal(struct {
+			Findings []finding `json:"findings"`
+		}{findings})
+		if err != nil {
+			return fmt.Errorf("JSON marshaling error: %w", err)
+		}
+		if err := render.WriteJSON(w, jsonData); err != nil {
+			return err
+		}
+	}
+	if len(findings) > 0 {
+		return &findingsError{check, len(findings), code}
+	}
+	return nil
+}
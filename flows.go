@@ -0,0 +1,151 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// flow is a dependency path from a source to a sink
+type flow struct {
+	Source string     `json:"source"`
+	Sink   string     `json:"sink"`
+	Paths  [][]string `json:"paths"`
+}
+
+// flowsCommand reports the dependency paths from source symbols to sink
+// symbols, e.g. from HTTP handlers to command execution, for security
+// review.
+func flowsCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("flows", flag.ExitOnError)
+	var sources, sinks listFlag
+	fs.Var(&sources, "source", "Source symbol `pattern`, may be repeated")
+	fs.Var(&sinks, "sink", "Sink symbol `pattern`, may be repeated")
+	all := fs.Bool("all", false, "Print all shortest paths between a source and a sink instead of one")
+	jsonMode := fs.Bool("json", false, "Output the paths as JSON")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope flows -source <pattern> -sink <pattern> [-all] [-json] <package-path> [<package-path>...]")
+		fmt.Fprintln(fs.Output(), "  Patterns match node IDs, where * matches any text, e.g. os/exec.Command or (database/sql.DB).*")
+		fmt.Fprintln(fs.Output(), "  Patterns starting with sig: match signatures instead, e.g. 'sig:*(w net/http.ResponseWriter, r *net/http.Request)'")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace || len(sources) == 0 || len(sinks) == 0 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		sourceMatch, err := compileSymbolPatterns(sources)
+		if err != nil {
+			return err
+		}
+		sinkMatch, err := compileSymbolPatterns(sinks)
+		if err != nil {
+			return err
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		flows := findFlows(g, sourceMatch, sinkMatch, *all)
+
+		if *jsonMode {
+			if flows == nil {
+				flows = []flow{}
+			}
+			jsonData, err := json.Marshal(flows)
+			if err != nil {
+				return fmt.Errorf("JSON marshaling error: %w", err)
+			}
+			return render.WriteJSON(os.Stdout, jsonData)
+		}
+		for _, f := range flows {
+			fmt.Printf("%s -> %s:\n", f.Source, f.Sink)
+			for _, path := range f.Paths {
+				fmt.Printf("\t%s\n", strings.Join(path, " -> "))
+			}
+		}
+		if len(flows) == 0 {
+			fmt.Fprintln(os.Stderr, "No paths from sources to sinks found")
+		}
+		return nil
+	}
+}
+
+// compileSymbolPatterns returns a function reporting whether a node matches
+// any of patterns. Patterns match the node ID, or the signature if prefixed
+// with "sig:", and * matches any text.
+func compileSymbolPatterns(patterns []string) (func(node *graph.Node) bool, error) {
+	var ids, sigs []*regexp.Regexp
+	for _, pattern := range patterns {
+		sig, isSig := strings.CutPrefix(pattern, "sig:")
+		if isSig {
+			pattern = sig
+		}
+		quoted := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+		re, err := regexp.Compile("^" + quoted + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if isSig {
+			sigs = append(sigs, re)
+		} else {
+			ids = append(ids, re)
+		}
+	}
+	return func(node *graph.Node) bool {
+		for _, re := range ids {
+			if re.MatchString(node.Id) {
+				return true
+			}
+		}
+		if node.Signature == "" {
+			return false
+		}
+		for _, re := range sigs {
+			if re.MatchString(node.Signature) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// findFlows returns the shortest dependency paths from every node matching
+// isSource to every node matching isSink it reaches, sorted by source and
+// sink. Only the first path of each pair is returned unless all is set.
+func findFlows(g *graph.Graph, isSource, isSink func(node *graph.Node) bool, all bool) []flow {
+	var sources, sinks []string
+	for _, node := range g.Nodes {
+		if isSource(node) {
+			sources = append(sources, node.Id)
+		}
+		if isSink(node) {
+			sinks = append(sinks, node.Id)
+		}
+	}
+	slices.Sort(sources)
+	slices.Sort(sinks)
+
+	succ := g.Successors()
+	var flows []flow
+	for _, source := range sources {
+		for _, sink := range sinks {
+			if paths := graph.ShortestPaths(succ, source, sink, all); len(paths) > 0 {
+				flows = append(flows, flow{source, sink, paths})
+			}
+		}
+	}
+	return flows
+}
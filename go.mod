@@ -2,9 +2,10 @@ module github.com/phyrog/sgope
 
 go 1.25.7
 
-require golang.org/x/tools v0.41.0
-
 require (
-	golang.org/x/mod v0.32.0 // indirect
-	golang.org/x/sync v0.19.0 // indirect
+	golang.org/x/mod v0.32.0
+	golang.org/x/tools v0.41.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sync v0.19.0 // indirect
@@ -0,0 +1,139 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+// PageRank parameters
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-9
+)
+
+// AddCentrality sets the PageRank and the betweenness centrality of every
+// node. Rank flows along links, i.e. from dependents to their dependencies,
+// so heavily depended-on nodes rank high. Betweenness is the fraction of
+// shortest paths between other nodes passing through a node, which is high
+// for nodes connecting otherwise separate parts of the code.
+func (g *Graph) AddCentrality() {
+	ids := slices.Sorted(maps.Keys(g.Nodes))
+	index := make(map[string]int, len(ids))
+	for i, nodeID := range ids {
+		index[nodeID] = i
+	}
+	succ := make([][]int, len(ids))
+	for from, tos := range g.Successors() {
+		i, ok := index[from]
+		if !ok {
+			continue
+		}
+		for _, to := range tos {
+			if j, ok := index[to]; ok && i != j {
+				succ[i] = append(succ[i], j)
+			}
+		}
+	}
+
+	pageRank := pageRank(succ)
+	betweenness := betweenness(succ)
+	for i, nodeID := range ids {
+		g.Nodes[nodeID].PageRank = pageRank[i]
+		g.Nodes[nodeID].Betweenness = betweenness[i]
+	}
+}
+
+// pageRank computes the PageRank of the nodes of a graph given as adjacency
+// lists by power iteration. The rank of nodes without successors is spread
+// over all nodes.
+func pageRank(succ [][]int) []float64 {
+	n := len(succ)
+	if n == 0 {
+		return nil
+	}
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for range pageRankIterations {
+		dangling := 0.0
+		for i := range next {
+			next[i] = 0
+		}
+		for i, tos := range succ {
+			if len(tos) == 0 {
+				dangling += rank[i]
+				continue
+			}
+			share := rank[i] / float64(len(tos))
+			for _, j := range tos {
+				next[j] += share
+			}
+		}
+		delta := 0.0
+		for i := range next {
+			next[i] = (1-pageRankDamping)/float64(n) + pageRankDamping*(next[i]+dangling/float64(n))
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	return rank
+}
+
+// betweenness computes the normalized betweenness centrality of the nodes of
+// a directed, unweighted graph given as adjacency lists, using Brandes'
+// algorithm.
+func betweenness(succ [][]int) []float64 {
+	n := len(succ)
+	centrality := make([]float64, n)
+	sigma := make([]float64, n)
+	dist := make([]int, n)
+	delta := make([]float64, n)
+	pred := make([][]int, n)
+	for s := range n {
+		for i := range n {
+			sigma[i], dist[i], delta[i], pred[i] = 0, -1, 0, pred[i][:0]
+		}
+		sigma[s], dist[s] = 1, 0
+		var order []int
+		queue := []int{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			order = append(order, v)
+			for _, w := range succ[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					pred[w] = append(pred[w], v)
+				}
+			}
+		}
+		for i := len(order) - 1; i >= 0; i-- {
+			w := order[i]
+			for _, v := range pred[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				centrality[w] += delta[w]
+			}
+		}
+	}
+	if n > 2 {
+		for i := range centrality {
+			centrality[i] /= float64((n - 1) * (n - 2))
+		}
+	}
+	return centrality
+}
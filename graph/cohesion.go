@@ -0,0 +1,87 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"slices"
+)
+
+// AddCohesion sets the LCOM4 lack of cohesion of every struct type with at
+// least two methods: the number of groups of methods that share no fields
+// and do not call each other. Types with an LCOM of more than one are
+// probably several types in one.
+func (g *Graph) AddCohesion() {
+	for _, group := range g.MethodGroups() {
+		g.Nodes[group.TypeID].LCOM = len(group.Groups)
+	}
+}
+
+// MethodGroup lists the methods of a struct type grouped by shared fields
+// and calls
+type MethodGroup struct {
+	TypeID string
+	Groups [][]string
+}
+
+// MethodGroups returns the method groups of all struct types with at least
+// two methods
+func (g *Graph) MethodGroups() []MethodGroup {
+	methods := make(map[string][]string)
+	for _, node := range g.Nodes {
+		if node.Type != FuncMethod {
+			continue
+		}
+		if parent, ok := g.Nodes[node.Parent]; ok && parent.Type == TypeStruct {
+			methods[parent.Id] = append(methods[parent.Id], node.Id)
+		}
+	}
+
+	// Union-find over methods and fields, joining every method with the
+	// fields and methods of its type it links to
+	parent := make(map[string]string)
+	var find func(x string) string
+	find = func(x string) string {
+		if p, ok := parent[x]; ok && p != x {
+			parent[x] = find(p)
+			return parent[x]
+		}
+		return x
+	}
+	union := func(a, b string) {
+		if ra, rb := find(a), find(b); ra != rb {
+			parent[ra] = rb
+		}
+	}
+	for _, link := range g.Links {
+		from, to := g.Nodes[link.From], g.Nodes[link.To]
+		if from == nil || to == nil || from.Type != FuncMethod || from.Parent != to.Parent {
+			continue
+		}
+		if to.Type == VarField || to.Type == FuncMethod {
+			union(from.Id, to.Id)
+		}
+	}
+
+	var groups []MethodGroup
+	for typeID, ms := range methods {
+		if len(ms) < 2 {
+			continue
+		}
+		byRoot := make(map[string][]string)
+		var roots []string
+		slices.Sort(ms)
+		for _, m := range ms {
+			root := find(m)
+			if _, ok := byRoot[root]; !ok {
+				roots = append(roots, root)
+			}
+			byRoot[root] = append(byRoot[root], m)
+		}
+		group := MethodGroup{TypeID: typeID}
+		for _, root := range roots {
+			group.Groups = append(group.Groups, byRoot[root])
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
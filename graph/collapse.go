@@ -0,0 +1,117 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Collapse merges nodes of g into summary nodes listing their members until
+// g has at most maxNodes nodes, keeping as much of the graph as possible.
+// Leaves, nodes linked to at most one other node, are collapsed first, the
+// leaves of a package linked to the same node into one summary node. If that
+// is not enough, whole packages are collapsed, the largest first. Package,
+// file and component nodes are only collapsed with their package.
+func (g *Graph) Collapse(maxNodes int) {
+	if maxNodes <= 0 || len(g.Nodes) <= maxNodes {
+		return
+	}
+	count := len(g.Nodes)
+	summaries := make(map[string]*Node)
+	n := 0
+	summarize := func(members []string, name string) {
+		n++
+		first := g.Nodes[members[0]]
+		summary := &Node{
+			Kind:      KindSummary,
+			Id:        fmt.Sprintf("summary#%d", n),
+			LocalName: name,
+			Pkg:       first.Pkg,
+			Module:    first.Module,
+			Members:   members,
+			Test:      true,
+		}
+		// Members may already be collapsed into the summary of their leaves
+		merged := make(map[*Node]bool)
+		for _, nodeID := range members {
+			merged[summaries[nodeID]] = true
+			summary.absorb(g.Nodes[nodeID])
+			summaries[nodeID] = summary
+		}
+		slices.Sort(summary.Platforms)
+		count -= len(members) - 1
+		for s := range merged {
+			if s != nil {
+				count += len(s.Members) - 1
+			}
+		}
+	}
+
+	type leafGroup struct {
+		pkg, neighbor string
+	}
+	neighbors := g.neighbors()
+	leaves := make(map[leafGroup][]string)
+	for nodeID, node := range g.Nodes {
+		if node.Kind == KindPackage || node.Kind == KindFile || node.Kind == KindComponent || len(neighbors[nodeID]) > 1 {
+			continue
+		}
+		group := leafGroup{pkg: node.Pkg}
+		if len(neighbors[nodeID]) == 1 {
+			group.neighbor = neighbors[nodeID][0]
+		}
+		leaves[group] = append(leaves[group], nodeID)
+	}
+	for _, members := range largestFirst(leaves) {
+		if count <= maxNodes || len(members) < 2 {
+			break
+		}
+		summarize(members, fmt.Sprintf("%s +%d", g.Nodes[members[0]].LocalName, len(members)-1))
+	}
+
+	if count > maxNodes {
+		pkgs := make(map[string][]string)
+		for nodeID, node := range g.Nodes {
+			pkgs[node.Pkg] = append(pkgs[node.Pkg], nodeID)
+		}
+		for _, members := range largestFirst(pkgs) {
+			if count <= maxNodes || len(members) < 2 {
+				break
+			}
+			pkg := g.Nodes[members[0]].Pkg
+			summarize(members, fmt.Sprintf("%s (%d)", pkg, len(members)))
+		}
+	}
+
+	collapsed := g.mergeNodes(func(node *Node) *Node {
+		if summary, ok := summaries[node.Id]; ok {
+			return summary
+		}
+		return node
+	})
+	for _, node := range collapsed.Nodes {
+		if summary, ok := summaries[node.Parent]; ok && node.Kind != KindSummary {
+			node.Parent = summary.Id
+		}
+	}
+	g.Nodes, g.Links = collapsed.Nodes, collapsed.Links
+}
+
+// largestFirst returns the sorted members of every group, in order of
+// decreasing group size
+func largestFirst[K comparable](groups map[K][]string) [][]string {
+	sorted := slices.Collect(maps.Values(groups))
+	for _, members := range sorted {
+		slices.Sort(members)
+	}
+	slices.SortFunc(sorted, func(a, b []string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a[0], b[0])
+	})
+	return sorted
+}
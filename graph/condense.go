@@ -0,0 +1,63 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Condense collapses every dependency cycle of g into a single component node
+// listing its members, which turns the graph into a DAG. Cycles are found like
+// by sgope cycles, so fields and closures are collapsed along with the
+// declaration they belong to.
+func (g *Graph) Condense() {
+	components := make(map[string]*Node)
+	for i, cycle := range g.OwnerGraph().Cycles() {
+		first := g.Nodes[cycle[0]]
+		component := &Node{
+			Kind:      KindComponent,
+			Id:        fmt.Sprintf("component#%d", i+1),
+			LocalName: fmt.Sprintf("%s +%d", first.LocalName, len(cycle)-1),
+			Pkg:       first.Pkg,
+			Module:    first.Module,
+			Members:   cycle,
+			Test:      true,
+		}
+		for _, nodeID := range cycle {
+			component.absorb(g.Nodes[nodeID])
+			components[nodeID] = component
+		}
+		slices.Sort(component.Platforms)
+	}
+
+	condensed := g.mergeNodes(func(node *Node) *Node {
+		if component, ok := components[g.Owner(node).Id]; ok {
+			return component
+		}
+		return node
+	})
+	for _, node := range condensed.Nodes {
+		if component, ok := components[node.Parent]; ok {
+			node.Parent = component.Id
+		}
+	}
+	g.Nodes, g.Links = condensed.Nodes, condensed.Links
+}
+
+// absorb adds the flags, size and platforms of member to the node standing
+// for it, e.g. a component node
+func (n *Node) absorb(member *Node) {
+	n.Test = n.Test && member.Test
+	n.External = n.External || member.External
+	n.Exported = n.Exported || member.Exported
+	n.Generated = n.Generated || member.Generated
+	n.Broken = n.Broken || member.Broken
+	n.Lines += member.Lines
+	n.Complexity += member.Complexity
+	for _, platform := range member.Platforms {
+		if !slices.Contains(n.Platforms, platform) {
+			n.Platforms = append(n.Platforms, platform)
+		}
+	}
+}
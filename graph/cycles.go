@@ -0,0 +1,320 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"container/heap"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// OwnerGraph returns a copy of g in which fields and closures are merged into
+// the declarations they belong to, so self-referential types and recursive
+// closures do not show up as cycles.
+func (g *Graph) OwnerGraph() *Graph {
+	return g.mergeNodes(g.Owner)
+}
+
+// DeclarationGraph returns a copy of g in which fields, closures and
+// methods are merged into the declarations they belong to.
+func (g *Graph) DeclarationGraph() *Graph {
+	return g.mergeNodes(func(node *Node) *Node {
+		node = g.Owner(node)
+		if node.Type == FuncMethod {
+			if parent, ok := g.Nodes[node.Parent]; ok {
+				return parent
+			}
+		}
+		return node
+	})
+}
+
+// Owner returns the declaration a field or closure belongs to, or node itself
+// for other nodes
+func (g *Graph) Owner(node *Node) *Node {
+	for node.Type == VarField || node.Type == FuncClosure {
+		parent, ok := g.Nodes[node.Parent]
+		if !ok {
+			break
+		}
+		node = parent
+	}
+	return node
+}
+
+// PackageGraph returns the graph of the packages of g, linked by the links
+// between their declarations.
+func (g *Graph) PackageGraph() *Graph {
+	packages := make(map[string]*Node)
+	return g.mergeNodes(func(node *Node) *Node {
+		if pkg, ok := packages[node.Pkg]; ok {
+			return pkg
+		}
+		packages[node.Pkg] = &Node{
+			Kind:      KindPackage,
+			Id:        node.Pkg,
+			LocalName: node.Pkg,
+			Pkg:       node.Pkg,
+			Module:    node.Module,
+			External:  node.External,
+		}
+		return packages[node.Pkg]
+	})
+}
+
+// mergeNodes returns the graph of the nodes that merge maps the nodes of g
+// to. Links between nodes mapped to the same node are dropped and the
+// weights of merged links are summed.
+func (g *Graph) mergeNodes(merge func(node *Node) *Node) *Graph {
+	merged := &Graph{Nodes: make(map[string]*Node), Labels: g.Labels, PositionStrings: g.PositionStrings}
+	ids := make(map[string]string, len(g.Nodes))
+	for _, node := range g.Nodes {
+		target := merge(node)
+		merged.Nodes[target.Id] = target
+		ids[node.Id] = target.Id
+	}
+	links := make(LinkSet)
+	for _, link := range g.Links {
+		from, to := ids[link.From], ids[link.To]
+		if from != to {
+			links[LinkKey{from, to, link.Kind}] += link.Weight
+		}
+	}
+	for link, weight := range links {
+		merged.Links = append(merged.Links, Link{From: link.From, To: link.To, Kind: link.Kind, Weight: weight})
+	}
+	return merged
+}
+
+// Cycles returns the strongly connected components of g with more than one
+// node, each sorted by node ID, in order of decreasing size.
+func (g *Graph) Cycles() [][]string {
+	return g.cycles(g.Successors())
+}
+
+// cycles is Cycles with the successors of g already computed
+func (g *Graph) cycles(succ map[string][]string) [][]string {
+	var cycles [][]string
+	for _, component := range g.sccs(succ) {
+		if len(component) > 1 {
+			cycles = append(cycles, component)
+		}
+	}
+	slices.SortStableFunc(cycles, func(a, b []string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a[0], b[0])
+	})
+	return cycles
+}
+
+// Successors returns the sorted IDs of the nodes each node links to. The
+// algorithms taking successors can share the result to avoid rebuilding it
+// for every call.
+func (g *Graph) Successors() map[string][]string {
+	adjacent := make(map[string]map[string]struct{})
+	for _, link := range g.Links {
+		if adjacent[link.From] == nil {
+			adjacent[link.From] = make(map[string]struct{})
+		}
+		adjacent[link.From][link.To] = struct{}{}
+	}
+	succ := make(map[string][]string, len(adjacent))
+	for from, tos := range adjacent {
+		succ[from] = slices.Sorted(maps.Keys(tos))
+	}
+	return succ
+}
+
+// SCCs returns the strongly connected components of g, each sorted by node
+// ID, in reverse topological order, i.e. every component only links to
+// components before it. Nodes that are not part of a cycle are components of
+// their own.
+func (g *Graph) SCCs() [][]string {
+	return g.sccs(g.Successors())
+}
+
+// sccs is SCCs with the successors of g already computed
+func (g *Graph) sccs(succ map[string][]string) [][]string {
+	components := StronglyConnected(g.Nodes, succ)
+	for _, component := range components {
+		slices.Sort(component)
+	}
+	return components
+}
+
+// TopoSort returns the IDs of the nodes of g ordered so that every node comes
+// before the nodes it links to, breaking ties by node ID. Links of a node to
+// itself are ignored. It fails if g has a dependency cycle between nodes,
+// which has no such order.
+func (g *Graph) TopoSort() ([]string, error) {
+	succ := g.Successors()
+	indegree := make(map[string]int, len(g.Nodes))
+	for from, tos := range succ {
+		if _, ok := g.Nodes[from]; !ok {
+			continue
+		}
+		for _, to := range tos {
+			if _, ok := g.Nodes[to]; ok && to != from {
+				indegree[to]++
+			}
+		}
+	}
+
+	// ready holds the nodes whose predecessors are all ordered, smallest ID
+	// first
+	ready := &idHeap{}
+	for nodeID := range g.Nodes {
+		if indegree[nodeID] == 0 {
+			heap.Push(ready, nodeID)
+		}
+	}
+	order := make([]string, 0, len(g.Nodes))
+	for ready.Len() > 0 {
+		nodeID := heap.Pop(ready).(string)
+		order = append(order, nodeID)
+		for _, to := range succ[nodeID] {
+			if _, ok := g.Nodes[to]; !ok || to == nodeID {
+				continue
+			}
+			indegree[to]--
+			if indegree[to] == 0 {
+				heap.Push(ready, to)
+			}
+		}
+	}
+	if len(order) < len(g.Nodes) {
+		path := CyclePath(succ, g.cycles(succ)[0])
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(append(path, path[0]), " -> "))
+	}
+	return order, nil
+}
+
+// idHeap is a min-heap of node IDs
+type idHeap []string
+
+func (h idHeap) Len() int           { return len(h) }
+func (h idHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h idHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *idHeap) Push(x any)        { *h = append(*h, x.(string)) }
+
+func (h *idHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// StronglyConnected returns the strongly connected components of the graph
+// given by nodes and succ, using Tarjan's algorithm. Components are returned
+// in reverse topological order, i.e. every component only links to
+// components before it.
+func StronglyConnected(nodes map[string]*Node, succ map[string][]string) [][]string {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var visit func(v string)
+	visit = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range succ[v] {
+			if _, ok := index[w]; !ok {
+				visit(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack[w] {
+				lowlink[v] = min(lowlink[v], index[w])
+			}
+		}
+
+		if lowlink[v] == index[v] {
+			var component []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, v := range slices.Sorted(maps.Keys(nodes)) {
+		if _, ok := index[v]; !ok {
+			visit(v)
+		}
+	}
+	return components
+}
+
+// CyclePath returns a shortest cycle through the first node of a strongly
+// connected component, staying inside the component.
+func (g *Graph) CyclePath(component []string) []string {
+	return CyclePath(g.Successors(), component)
+}
+
+// CyclePath returns a shortest cycle through the first node of a strongly
+// connected component of the graph given by succ, staying inside the
+// component.
+func CyclePath(succ map[string][]string, component []string) []string {
+	inComponent := make(map[string]bool, len(component))
+	for _, nodeID := range component {
+		inComponent[nodeID] = true
+	}
+	start := component[0]
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range succ[v] {
+			if !inComponent[w] {
+				continue
+			}
+			if w == start {
+				path := []string{v}
+				for v != start {
+					v = prev[v]
+					path = append(path, v)
+				}
+				slices.Reverse(path)
+				return path
+			}
+			if _, ok := prev[w]; !ok {
+				prev[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return []string{start}
+}
+
+// CycleGraph returns the subgraph of g made of the nodes of cycles and the
+// links between nodes of the same cycle. Nodes are shared with g.
+func (g *Graph) CycleGraph(cycles [][]string) *Graph {
+	sub := &Graph{Nodes: make(map[string]*Node), Labels: g.Labels, PositionStrings: g.PositionStrings}
+	cycleOf := make(map[string]int)
+	for i, cycle := range cycles {
+		for _, nodeID := range cycle {
+			sub.Nodes[nodeID] = g.Nodes[nodeID]
+			cycleOf[nodeID] = i + 1
+		}
+	}
+	for _, link := range g.Links {
+		if c := cycleOf[link.From]; c != 0 && c == cycleOf[link.To] {
+			sub.Links = append(sub.Links, link)
+		}
+	}
+	return sub
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTopoSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		edges   string
+		want    []string
+		wantErr string
+	}{
+		{"empty", "", []string{}, ""},
+		{"chain", "c->b b->a", []string{"c", "b", "a"}, ""},
+		// Ties are broken by node ID
+		{"diamond", "a->c a->b b->d c->d", []string{"a", "b", "c", "d"}, ""},
+		{"unconnected nodes", "b a", []string{"a", "b"}, ""},
+		{"self-loop", "a->a a->b", []string{"a", "b"}, ""},
+		{"two-node cycle", "a->b b->a b->c", nil, "dependency cycle: a -> b -> a"},
+		{"longer cycle", "x->a a->b b->c c->a", nil, "dependency cycle: a -> b -> c -> a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testGraph(tt.edges).TopoSort()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("TopoSort() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("TopoSort() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStronglyConnected(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges string
+		want  [][]string
+	}{
+		// Components come in reverse topological order
+		{"chain", "a->b b->c", [][]string{{"c"}, {"b"}, {"a"}}},
+		{"self-loop", "a->a a->b", [][]string{{"b"}, {"a"}}},
+		{"two-node cycle", "a->b b->a", [][]string{{"a", "b"}}},
+		{"two cycles", "a->b b->a b->c c->d d->c", [][]string{{"c", "d"}, {"a", "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGraph(tt.edges)
+			got := StronglyConnected(g.Nodes, g.Successors())
+			for _, component := range got {
+				slices.Sort(component)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("StronglyConnected() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCyclePath(t *testing.T) {
+	tests := []struct {
+		name      string
+		edges     string
+		component []string
+		want      []string
+	}{
+		{"self-loop", "a->a", []string{"a"}, []string{"a"}},
+		{"no cycle", "a->b", []string{"a"}, []string{"a"}},
+		{"two-node cycle", "a->b b->a", []string{"a", "b"}, []string{"a", "b"}},
+		// The shortest cycle through a is taken, not the one through all
+		// nodes
+		{"shortest cycle", "a->b b->c c->a b->a", []string{"a", "b", "c"}, []string{"a", "b"}},
+		// Links leaving the component are not followed
+		{"inside component", "a->x x->a a->b b->a", []string{"a", "b"}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testGraph(tt.edges).CyclePath(tt.component); !slices.Equal(got, tt.want) {
+				t.Errorf("CyclePath(%q) = %q, want %q", tt.component, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+// AddFanMetrics sets the number of distinct nodes linking to and linked from
+// every node, and the same for the packages of the nodes.
+func (g *Graph) AddFanMetrics() {
+	fan := g.FanCounts()
+	for _, node := range g.Nodes {
+		node.FanIn = fan.In[node.Id]
+		node.FanOut = fan.Out[node.Id]
+		node.PkgFanIn = fan.PkgIn[node.Pkg]
+		node.PkgFanOut = fan.PkgOut[node.Pkg]
+	}
+}
+
+// FanCounts holds the fan-in and fan-out of the nodes and packages of a graph
+type FanCounts struct {
+	In, Out       map[string]int
+	PkgIn, PkgOut map[string]int
+}
+
+// FanCounts counts the distinct nodes linking to and linked from every node,
+// and the distinct other packages linking to and linked from every package.
+func (g *Graph) FanCounts() FanCounts {
+	in := make(map[string]map[string]bool)
+	out := make(map[string]map[string]bool)
+	pkgIn := make(map[string]map[string]bool)
+	pkgOut := make(map[string]map[string]bool)
+	add := func(m map[string]map[string]bool, key, value string) {
+		if m[key] == nil {
+			m[key] = make(map[string]bool)
+		}
+		m[key][value] = true
+	}
+	for _, link := range g.Links {
+		if link.From == link.To {
+			continue
+		}
+		add(in, link.To, link.From)
+		add(out, link.From, link.To)
+		from, to := g.Nodes[link.From], g.Nodes[link.To]
+		if from == nil || to == nil || from.Pkg == to.Pkg {
+			continue
+		}
+		add(pkgIn, to.Pkg, from.Pkg)
+		add(pkgOut, from.Pkg, to.Pkg)
+	}
+	count := func(m map[string]map[string]bool) map[string]int {
+		counts := make(map[string]int, len(m))
+		for key, values := range m {
+			counts[key] = len(values)
+		}
+		return counts
+	}
+	return FanCounts{count(in), count(out), count(pkgIn), count(pkgOut)}
+}
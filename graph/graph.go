@@ -0,0 +1,342 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+// Package graph defines the dependency graph of Go declarations produced by
+// package analysis, its JSON format, and algorithms working on it.
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// SchemaVersion is the version of the JSON graph format. It is increased
+// whenever a change could break consumers of the format.
+const SchemaVersion = 1
+
+// Node kinds, types and link kinds
+const (
+	KindType    = "type"
+	KindFunc    = "func"
+	KindConst   = "const"
+	KindVar     = "var"
+	KindPackage = "package"
+	// KindFile nodes stand for source files, emitted with -file-nodes
+	KindFile = "file"
+	// KindComponent nodes stand for a dependency cycle collapsed by -condense
+	KindComponent = "component"
+	// KindSummary nodes stand for declarations collapsed to stay below a
+	// node limit, see Graph.Collapse
+	KindSummary = "summary"
+
+	TypeStruct    = "struct"
+	TypeInterface = "interface"
+	TypeBasic     = "basic"
+	TypeFunc      = "func"
+	TypeName      = "name"
+	TypeAlias     = "alias"
+
+	FuncMethod  = "method"
+	FuncBasic   = "func"
+	FuncClosure = "closure"
+	// Test, benchmark, fuzz and example functions run by go test
+	FuncTest      = "test"
+	FuncBenchmark = "benchmark"
+	FuncFuzz      = "fuzz"
+	FuncExample   = "example"
+
+	VarBasic = "basic"
+	VarField = "field"
+
+	ConstGroup = "group"
+
+	// PkgDirectory package nodes stand for directories without a package
+	// above analyzed packages, emitted with -hierarchy
+	PkgDirectory = "directory"
+
+	// Link kinds
+	LinkReference    = "reference"    // identifier reference in a declaration
+	LinkCall         = "call"         // call edge from the SSA call graph
+	LinkValue        = "value"        // function or method used as a value
+	LinkFieldType    = "field-type"   // struct field to the type of the field
+	LinkEmbeds       = "embeds"       // struct or interface to an embedded type
+	LinkImplements   = "implements"   // concrete type to a satisfied interface
+	LinkMethodOf     = "method-of"    // method to its receiver type or interface
+	LinkParent       = "parent"       // field or closure to its enclosing node
+	LinkConstraint   = "constraint"   // generic declaration to a constraint type
+	LinkInstantiates = "instantiates" // generic instance to its origin
+	LinkAliases      = "aliases"      // type alias to the aliased type
+	LinkImports      = "imports"      // package to the init functions of a blank import
+	LinkAsserts      = "asserts"      // type assertion or type switch case to the type
+	LinkConvertsTo   = "converts-to"  // explicit conversion T(x) to the type
+	LinkConstructs   = "constructs"   // composite literal T{...} to the type
+	LinkGo           = "go"           // reference inside a go statement
+	LinkDefer        = "defer"        // reference inside a defer statement
+	LinkDemonstrates = "demonstrates" // example function to the symbol it documents
+	LinkContains     = "contains"     // directory, package or file to what it contains
+	LinkLinkname     = "linkname"     // symbol to the symbol it is linked to with //go:linkname
+	LinkProvides     = "provides"     // dependency injection provider to the type it provides
+	LinkConsumes     = "consumes"     // injected declaration to the providers of its dependencies
+)
+
+// Graph is a dependency graph of declarations, keyed by node ID
+type Graph struct {
+	Nodes map[string]*Node `json:"nodes"`
+	Links []Link           `json:"links"`
+	// Labels maps short node IDs to the full IDs they replace
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// PositionStrings emits node positions in the "file:line:col-line:col"
+	// format of earlier versions
+	PositionStrings bool `json:"-"`
+}
+
+// MarshalJSON encodes g in the versioned JSON graph format, with the nodes
+// as a list sorted by ID
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	var out struct {
+		SchemaVersion int `json:"schemaVersion"`
+		Graph
+		Nodes []*Node `json:"nodes"`
+	}
+	out.SchemaVersion = SchemaVersion
+
+	out.Links = g.Links
+	out.Labels = g.Labels
+
+	for _, nodeID := range slices.Sorted(maps.Keys(g.Nodes)) {
+		out.Nodes = append(out.Nodes, g.Nodes[nodeID])
+	}
+
+	if g.PositionStrings {
+		type legacyNode struct {
+			*Node
+			Position string `json:"position,omitempty"`
+		}
+		var legacy struct {
+			SchemaVersion int               `json:"schemaVersion"`
+			Links         []Link            `json:"links"`
+			Labels        map[string]string `json:"labels,omitempty"`
+			Nodes         []legacyNode      `json:"nodes"`
+		}
+		legacy.SchemaVersion = SchemaVersion
+		legacy.Links = out.Links
+		legacy.Labels = g.Labels
+		for _, node := range out.Nodes {
+			var position string
+			if node.Position != nil {
+				position = node.Position.String()
+			}
+			legacy.Nodes = append(legacy.Nodes, legacyNode{node, position})
+		}
+		return json.Marshal(legacy)
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes g from the JSON graph format written by MarshalJSON,
+// including positions in the string format of earlier versions. Short IDs
+// are kept, ExpandIDs restores the full IDs.
+func (g *Graph) UnmarshalJSON(data []byte) error {
+	var in struct {
+		SchemaVersion int               `json:"schemaVersion"`
+		Nodes         []*Node           `json:"nodes"`
+		Links         []Link            `json:"links"`
+		Labels        map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("unsupported schemaVersion %d, this version of sgope supports up to %d", in.SchemaVersion, SchemaVersion)
+	}
+
+	g.Nodes = make(map[string]*Node, len(in.Nodes))
+	for _, node := range in.Nodes {
+		if _, ok := g.Nodes[node.Id]; ok {
+			return fmt.Errorf("duplicate node %q", node.Id)
+		}
+		g.Nodes[node.Id] = node
+	}
+	g.Links = in.Links
+	g.Labels = in.Labels
+	return nil
+}
+
+// Node is a declaration, or a file, package or directory containing
+// declarations
+type Node struct {
+	Kind      string    `json:"kind"`
+	Type      string    `json:"type,omitempty"`
+	Pkg       string    `json:"pkg"`
+	Module    string    `json:"module,omitempty"`
+	Id        string    `json:"id"`
+	LocalName string    `json:"name"`
+	Parent    string    `json:"parent,omitempty"`
+	Test      bool      `json:"test,omitempty"`
+	Position  *Position `json:"position,omitempty"`
+	External  bool      `json:"external,omitempty"`
+	Platforms []string  `json:"platforms,omitempty"`
+	// Exported is set for declarations that are part of the public API of
+	// their package, i.e. exported and, for methods and fields, declared on
+	// an exported type.
+	Exported bool `json:"exported,omitempty"`
+	// Signature is the declaration header of functions, methods and
+	// closures, with types qualified relative to their package.
+	Signature string `json:"signature,omitempty"`
+	// Generated is set for declarations in files with a
+	// "// Code generated ... DO NOT EDIT." header.
+	Generated bool `json:"generated,omitempty"`
+	// Broken is set for declarations of packages with load or type errors,
+	// whose links may be incomplete.
+	Broken bool `json:"broken,omitempty"`
+	// ReceiverPointer is set for methods with a pointer receiver, which is
+	// not part of their ID.
+	ReceiverPointer bool `json:"receiverPointer,omitempty"`
+	// Lines and Complexity measure the size and cyclomatic complexity of
+	// functions, methods and closures.
+	Lines      int `json:"lines,omitempty"`
+	Complexity int `json:"complexity,omitempty"`
+	// FanIn and FanOut count the distinct nodes linking to and linked from
+	// the node, PkgFanIn and PkgFanOut the distinct packages linking to and
+	// linked from the node's package.
+	FanIn     int `json:"fanIn,omitempty"`
+	FanOut    int `json:"fanOut,omitempty"`
+	PkgFanIn  int `json:"pkgFanIn,omitempty"`
+	PkgFanOut int `json:"pkgFanOut,omitempty"`
+	// LCOM is the lack of cohesion of struct types with at least two
+	// methods, the number of groups of methods sharing no fields.
+	LCOM int `json:"lcom,omitempty"`
+	// PageRank and Betweenness are the centrality of the node, computed
+	// with -centrality.
+	PageRank    float64 `json:"pageRank,omitempty"`
+	Betweenness float64 `json:"betweenness,omitempty"`
+	// Members lists the IDs of the declarations collapsed into a component
+	// node by -condense or a summary node by -max-nodes.
+	Members []string `json:"members,omitempty"`
+	// Reflected is set for types passed to reflect or encoding/* functions,
+	// and the types of their fields, whose methods and fields may be used
+	// without a link.
+	Reflected bool `json:"reflected,omitempty"`
+	// Unsafe and Cgo are set for declarations that refer to the unsafe
+	// package or to C symbols through cgo.
+	Unsafe bool `json:"unsafe,omitempty"`
+	Cgo    bool `json:"cgo,omitempty"`
+	// Directives lists the //go:generate, //go:linkname and //go:embed
+	// directives of the declaration, without the leading //.
+	Directives []string `json:"directives,omitempty"`
+	// Group is the group assigned to the node by the first classifier
+	// matching it, e.g. a -classify rule.
+	Group string `json:"group,omitempty"`
+	// Attributes holds the attributes contributed by -plugin analyzers and
+	// by commands annotating their output, see WithAttribute.
+	Attributes map[string]any `json:"attributes,omitempty"`
+	// Vulnerable lists the vulnerabilities whose vulnerable symbol the node
+	// is, or stands for if the symbol is not part of the graph, and Vulns
+	// the vulnerabilities the node reaches. Both are set with -vulns.
+	Vulnerable []string `json:"vulnerable,omitempty"`
+	Vulns      []string `json:"vulns,omitempty"`
+	// TestedBy lists the IDs of the test functions exercising the node,
+	// computed with -tested-by.
+	TestedBy []string `json:"testedBy,omitempty"`
+	// Object and Package are the declaration and package the node was
+	// built from. They are not part of the JSON format and are nil for
+	// nodes read from JSON.
+	Object  types.Object      `json:"-"`
+	Package *packages.Package `json:"-"`
+}
+
+// Link is a dependency of one node on another
+type Link struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Kind   string `json:"kind"`
+	Weight int    `json:"weight"`
+	// Attributes holds the attributes of commands annotating their output,
+	// see WithAttribute.
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// WithAttribute returns a copy of n with an attribute set, leaving n
+// unchanged
+func (n *Node) WithAttribute(key string, value any) *Node {
+	copied := *n
+	copied.Attributes = maps.Clone(n.Attributes)
+	if copied.Attributes == nil {
+		copied.Attributes = make(map[string]any)
+	}
+	copied.Attributes[key] = value
+	return &copied
+}
+
+// WithAttribute returns a copy of l with an attribute set
+func (l Link) WithAttribute(key string, value any) Link {
+	attributes := maps.Clone(l.Attributes)
+	if attributes == nil {
+		attributes = make(map[string]any)
+	}
+	attributes[key] = value
+	l.Attributes = attributes
+	return l
+}
+
+// LinkKey identifies the links of a LinkSet
+type LinkKey struct {
+	From, To, Kind string
+}
+
+// LinkSet counts the occurrences of every link, e.g. the number of references
+// from one declaration to another
+type LinkSet map[LinkKey]int
+
+// Insert counts an occurrence of the link from from to to of kind
+func (ls LinkSet) Insert(from, to, kind string) {
+	ls[LinkKey{from, to, kind}]++
+}
+
+// Position is a range in a source file. Lines and columns start at 1, and
+// the file name is relative to the module root if possible.
+type Position struct {
+	File      string `json:"file"`
+	StartLine int    `json:"startLine"`
+	StartCol  int    `json:"startCol"`
+	EndLine   int    `json:"endLine"`
+	EndCol    int    `json:"endCol"`
+}
+
+// UnmarshalJSON decodes p from an object or a "file:line:col-line:col"
+// string
+func (p *Position) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		type position Position
+		return json.Unmarshal(data, (*position)(p))
+	}
+	// The file name may contain colons, so it ends at the second to last
+	// colon before the last dash
+	file := -1
+	if dash := strings.LastIndex(s, "-"); dash >= 0 {
+		if col := strings.LastIndex(s[:dash], ":"); col >= 0 {
+			file = strings.LastIndex(s[:col], ":")
+		}
+	}
+	if file < 0 {
+		return fmt.Errorf("invalid position %q", s)
+	}
+	p.File = s[:file]
+	if _, err := fmt.Sscanf(s[file+1:], "%d:%d-%d:%d", &p.StartLine, &p.StartCol, &p.EndLine, &p.EndCol); err != nil {
+		return fmt.Errorf("invalid position %q", s)
+	}
+	return nil
+}
+
+// String formats p as "file:line:col-line:col"
+func (p *Position) String() string {
+	return fmt.Sprintf("%s:%d:%d-%d:%d", p.File, p.StartLine, p.StartCol, p.EndLine, p.EndCol)
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"slices"
+)
+
+// Merge adds the nodes and links of other to g. Nodes present in both graphs
+// are kept from g, with their platforms and vulnerabilities combined, unless
+// only the node of other is part of the analyzed packages, e.g. when merging
+// shards.
+func (g *Graph) Merge(other *Graph) {
+	for nodeID, node := range other.Nodes {
+		existing, ok := g.Nodes[nodeID]
+		if !ok {
+			g.Nodes[nodeID] = node
+			continue
+		}
+		if existing.External && !node.External {
+			g.Nodes[nodeID] = node
+			existing, node = node, existing
+		}
+		for _, platform := range node.Platforms {
+			if !slices.Contains(existing.Platforms, platform) {
+				existing.Platforms = append(existing.Platforms, platform)
+			}
+		}
+		for _, osv := range node.Vulnerable {
+			if !slices.Contains(existing.Vulnerable, osv) {
+				existing.Vulnerable = append(existing.Vulnerable, osv)
+			}
+		}
+		for _, osv := range node.Vulns {
+			if !slices.Contains(existing.Vulns, osv) {
+				existing.Vulns = append(existing.Vulns, osv)
+			}
+		}
+	}
+
+	// Links present in both graphs keep the higher weight
+	seen := make(map[LinkKey]int, len(g.Links))
+	for i, link := range g.Links {
+		seen[LinkKey{link.From, link.To, link.Kind}] = i
+	}
+	for _, link := range other.Links {
+		key := LinkKey{link.From, link.To, link.Kind}
+		i, ok := seen[key]
+		if !ok {
+			seen[key] = len(g.Links)
+			g.Links = append(g.Links, link)
+			continue
+		}
+		g.Links[i].Weight = max(g.Links[i].Weight, link.Weight)
+	}
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FindNode returns the node with the given ID, or the only node whose ID
+// ends in name after a package path separator or a dot.
+func (g *Graph) FindNode(name string) (*Node, error) {
+	if node, ok := g.Nodes[name]; ok {
+		return node, nil
+	}
+	var matches []string
+	for nodeID := range g.Nodes {
+		if strings.HasSuffix(nodeID, "/"+name) || strings.HasSuffix(nodeID, "."+name) || strings.HasSuffix(nodeID, "("+name) {
+			matches = append(matches, nodeID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no node matches %q", name)
+	case 1:
+		return g.Nodes[matches[0]], nil
+	}
+	slices.Sort(matches)
+	return nil, fmt.Errorf("%q is ambiguous, it matches:\n\t%s", name, strings.Join(matches, "\n\t"))
+}
+
+// ShortestPath returns the first shortest path over the links of g from one
+// node to another in order of node IDs, or nil if to cannot be reached from
+// from.
+func (g *Graph) ShortestPath(from, to string) []string {
+	if paths := g.ShortestPaths(from, to, false); len(paths) > 0 {
+		return paths[0]
+	}
+	return nil
+}
+
+// ShortestPaths returns the shortest paths over the links of g from one node
+// to another, or only the first of them in order of node IDs unless all is
+// set. It returns nil if to cannot be reached from from.
+func (g *Graph) ShortestPaths(from, to string, all bool) [][]string {
+	return ShortestPaths(g.Successors(), from, to, all)
+}
+
+// ShortestPaths returns the shortest paths of the graph given by succ from
+// one node to another like Graph.ShortestPaths, for callers looking up many
+// paths of the same graph.
+func ShortestPaths(succ map[string][]string, from, to string, all bool) [][]string {
+	dist := map[string]int{from: 0}
+	// prev lists the predecessors of every node on shortest paths from from
+	prev := make(map[string][]string)
+	queue := []string{from}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if v == to {
+			break
+		}
+		for _, w := range succ[v] {
+			d, seen := dist[w]
+			if !seen {
+				dist[w] = dist[v] + 1
+				queue = append(queue, w)
+			}
+			if !seen || d == dist[v]+1 {
+				prev[w] = append(prev[w], v)
+			}
+		}
+	}
+	if _, ok := dist[to]; !ok || from == to {
+		return nil
+	}
+
+	var paths [][]string
+	var walk func(nodeID string, suffix []string)
+	walk = func(nodeID string, suffix []string) {
+		if !all && len(paths) > 0 {
+			return
+		}
+		suffix = append([]string{nodeID}, suffix...)
+		if nodeID == from {
+			paths = append(paths, suffix)
+			return
+		}
+		for _, p := range prev[nodeID] {
+			walk(p, suffix)
+		}
+	}
+	walk(to, nil)
+	return paths
+}
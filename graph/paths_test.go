@@ -0,0 +1,36 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestShortestPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		edges    string
+		from, to string
+		all      bool
+		want     [][]string
+	}{
+		{"direct link", "a->b a->c c->b", "a", "b", true, [][]string{{"a", "b"}}},
+		{"equal-length paths", "a->c a->b b->d c->d", "a", "d", true, [][]string{{"a", "b", "d"}, {"a", "c", "d"}}},
+		// Without all, only the first path in order of node IDs is returned
+		{"first of equal-length paths", "a->c a->b b->d c->d", "a", "d", false, [][]string{{"a", "b", "d"}}},
+		{"longer paths ignored", "a->b b->c c->d a->d", "a", "d", true, [][]string{{"a", "d"}}},
+		{"no path", "a->b c->b", "a", "c", true, nil},
+		{"against links", "a->b", "b", "a", true, nil},
+		{"same node", "a->b b->a", "a", "a", true, nil},
+		{"unknown node", "a->b", "a", "missing", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testGraph(tt.edges).ShortestPaths(tt.from, tt.to, tt.all)
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("ShortestPaths(%q, %q) = %q, want %q", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
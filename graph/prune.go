@@ -0,0 +1,64 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Filter drops the nodes of g for which keep returns false, along with their
+// links
+func (g *Graph) Filter(keep func(node *Node) bool) {
+	maps.DeleteFunc(g.Nodes, func(_ string, node *Node) bool {
+		return !keep(node)
+	})
+	g.Links = slices.DeleteFunc(g.Links, func(link Link) bool {
+		return g.Nodes[link.From] == nil || g.Nodes[link.To] == nil
+	})
+}
+
+// PruneLinks drops the links of g whose weight is below minWeight. If topK is
+// positive, it also drops the links that are not among the topK heaviest
+// links of either node they connect, so every node keeps its strongest
+// dependencies and dependents. Links of equal weight are ranked by the IDs of
+// the nodes they connect.
+func (g *Graph) PruneLinks(minWeight, topK int) {
+	links := slices.DeleteFunc(g.Links, func(link Link) bool {
+		return link.Weight < minWeight
+	})
+	if topK > 0 {
+		incident := make(map[string][]int)
+		for i, link := range links {
+			incident[link.From] = append(incident[link.From], i)
+			if link.To != link.From {
+				incident[link.To] = append(incident[link.To], i)
+			}
+		}
+		keep := make([]bool, len(links))
+		for _, indices := range incident {
+			slices.SortFunc(indices, func(a, b int) int {
+				la, lb := links[a], links[b]
+				return cmp.Or(
+					cmp.Compare(lb.Weight, la.Weight),
+					strings.Compare(la.From, lb.From),
+					strings.Compare(la.To, lb.To),
+					strings.Compare(la.Kind, lb.Kind),
+				)
+			})
+			for _, i := range indices[:min(topK, len(indices))] {
+				keep[i] = true
+			}
+		}
+		kept := links[:0]
+		for i, link := range links {
+			if keep[i] {
+				kept = append(kept, link)
+			}
+		}
+		links = kept
+	}
+	g.Links = links
+}
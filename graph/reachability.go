@@ -0,0 +1,127 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"strings"
+)
+
+// MethodName returns the name of a method node without its receiver type
+func MethodName(node *Node) string {
+	return node.LocalName[strings.LastIndex(node.LocalName, ".")+1:]
+}
+
+// Reachability indexes the links, children and method sets of a graph to
+// compute which nodes are reachable from others
+type Reachability struct {
+	out          map[string][]string
+	children     map[string][]string
+	methods      map[string]map[string]string
+	implementers map[string][]string
+	embeds       map[string][]string
+}
+
+// Reachability indexes g for reachability queries
+func (g *Graph) Reachability() *Reachability {
+	r := &Reachability{
+		out:          make(map[string][]string),
+		children:     make(map[string][]string),
+		methods:      make(map[string]map[string]string),
+		implementers: make(map[string][]string),
+		embeds:       make(map[string][]string),
+	}
+	for _, link := range g.Links {
+		r.out[link.From] = append(r.out[link.From], link.To)
+		switch link.Kind {
+		case LinkImplements:
+			r.implementers[link.To] = append(r.implementers[link.To], link.From)
+		case LinkEmbeds:
+			r.embeds[link.From] = append(r.embeds[link.From], link.To)
+		}
+	}
+	for _, node := range g.Nodes {
+		switch node.Type {
+		case VarField, FuncClosure:
+			r.children[node.Parent] = append(r.children[node.Parent], node.Id)
+		case FuncMethod:
+			if r.methods[node.Parent] == nil {
+				r.methods[node.Parent] = make(map[string]string)
+			}
+			r.methods[node.Parent][MethodName(node)] = node.Id
+			// Reflection may call any method of a reflected type
+			if parent, ok := g.Nodes[node.Parent]; ok && parent.Reflected {
+				r.children[node.Parent] = append(r.children[node.Parent], node.Id)
+			}
+		}
+	}
+	return r
+}
+
+// From returns the IDs of the nodes reachable from roots, including the
+// roots. A node is reachable if a reachable node links to it, if it is a
+// field or closure of a reachable node, if it is a method of a reachable
+// type used through reflection, or if it is a method of a reachable type
+// that implements a reachable interface method, possibly through embedding.
+func (r *Reachability) From(roots []string) map[string]bool {
+	reachable := make(map[string]bool)
+	var queue []string
+	mark := func(nodeID string) {
+		if !reachable[nodeID] {
+			reachable[nodeID] = true
+			queue = append(queue, nodeID)
+		}
+	}
+	for _, nodeID := range roots {
+		mark(nodeID)
+	}
+
+	for len(queue) > 0 {
+		for len(queue) > 0 {
+			nodeID := queue[0]
+			queue = queue[1:]
+			for _, to := range r.out[nodeID] {
+				mark(to)
+			}
+			for _, child := range r.children[nodeID] {
+				mark(child)
+			}
+		}
+
+		// Dynamic dispatch: a reachable interface method reaches the
+		// methods of the same name of every reachable implementation.
+		for ifaceID, impls := range r.implementers {
+			for name, ifaceMethod := range r.methods[ifaceID] {
+				if !reachable[ifaceMethod] {
+					continue
+				}
+				for _, impl := range impls {
+					if !reachable[impl] {
+						continue
+					}
+					if methodID := r.findMethod(impl, name, make(map[string]bool)); methodID != "" {
+						mark(methodID)
+					}
+				}
+			}
+		}
+	}
+	return reachable
+}
+
+// findMethod resolves a method by name on a type and the types it embeds,
+// like a method set would.
+func (r *Reachability) findMethod(typeID, name string, seen map[string]bool) string {
+	if seen[typeID] {
+		return ""
+	}
+	seen[typeID] = true
+	if methodID, ok := r.methods[typeID][name]; ok {
+		return methodID
+	}
+	for _, embedded := range r.embeds[typeID] {
+		if methodID := r.findMethod(embedded, name, seen); methodID != "" {
+			return methodID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+// Reduce removes the links implied by transitivity: a link from a to b is
+// dropped if b can also be reached from a through other nodes. Links within a
+// dependency cycle are kept, since there is no unique reduction of a cycle.
+// All kinds of links between a and b are dropped together.
+func (g *Graph) Reduce() {
+	succ := g.Successors()
+	components := StronglyConnected(g.Nodes, succ)
+	componentOf := make(map[string]int, len(g.Nodes))
+	for i, component := range components {
+		for _, nodeID := range component {
+			componentOf[nodeID] = i
+		}
+	}
+
+	// Successor components of every component, excluding itself
+	next := make([]map[int]bool, len(components))
+	for i, component := range components {
+		next[i] = make(map[int]bool)
+		for _, nodeID := range component {
+			for _, to := range succ[nodeID] {
+				if c := componentOf[to]; c != i {
+					next[i][c] = true
+				}
+			}
+		}
+	}
+
+	// Components only link to components before them, so the components
+	// reachable from each one are known by the time it is visited.
+	reach := make([]bitset, len(components))
+	for i := range components {
+		reach[i] = newBitset(len(components))
+		for c := range next[i] {
+			reach[i].set(c)
+			reach[i].or(reach[c])
+		}
+	}
+
+	redundant := func(from, to int) bool {
+		for c := range next[from] {
+			if c != to && reach[c].has(to) {
+				return true
+			}
+		}
+		return false
+	}
+
+	links := g.Links[:0]
+	for _, link := range g.Links {
+		from, to := componentOf[link.From], componentOf[link.To]
+		if from == to || !redundant(from, to) {
+			links = append(links, link)
+		}
+	}
+	g.Links = links
+}
+
+// bitset is a fixed-size set of small integers
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int) { b[i/64] |= 1 << (i % 64) }
+
+func (b bitset) has(i int) bool { return b[i/64]&(1<<(i%64)) != 0 }
+
+func (b bitset) or(other bitset) {
+	for i := range b {
+		b[i] |= other[i]
+	}
+}
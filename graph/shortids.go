@@ -0,0 +1,82 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+)
+
+// shortIDLength is the minimum number of hex digits of a short node ID
+const shortIDLength = 8
+
+// ShortenIDs replaces every node ID by a short stable hash of it and records
+// the full IDs in the label table. Hashes are lengthened as needed to keep
+// IDs unique.
+func (g *Graph) ShortenIDs() {
+	short := make(map[string]string, len(g.Nodes))
+	used := make(map[string]bool, len(g.Nodes))
+	for _, nodeID := range slices.Sorted(maps.Keys(g.Nodes)) {
+		sum := sha256.Sum256([]byte(nodeID))
+		hash := hex.EncodeToString(sum[:])
+		n := shortIDLength
+		for used[hash[:n]] && n < len(hash) {
+			n++
+		}
+		short[nodeID] = hash[:n]
+		used[hash[:n]] = true
+	}
+
+	nodes := make(map[string]*Node, len(g.Nodes))
+	g.Labels = make(map[string]string, len(g.Nodes))
+	for nodeID, node := range g.Nodes {
+		node.Id = short[nodeID]
+		if parent, ok := short[node.Parent]; ok {
+			node.Parent = parent
+		}
+		for i, test := range node.TestedBy {
+			node.TestedBy[i] = short[test]
+		}
+		nodes[node.Id] = node
+		g.Labels[node.Id] = nodeID
+	}
+	g.Nodes = nodes
+
+	for i, link := range g.Links {
+		g.Links[i].From = short[link.From]
+		g.Links[i].To = short[link.To]
+	}
+}
+
+// ExpandIDs replaces the short IDs of a graph read with -short-ids output by
+// the full IDs of the label table, which is cleared
+func (g *Graph) ExpandIDs() {
+	if len(g.Labels) == 0 {
+		return
+	}
+	expand := func(nodeID string) string {
+		if full, ok := g.Labels[nodeID]; ok {
+			return full
+		}
+		return nodeID
+	}
+
+	nodes := make(map[string]*Node, len(g.Nodes))
+	for _, node := range g.Nodes {
+		node.Id = expand(node.Id)
+		node.Parent = expand(node.Parent)
+		for i, test := range node.TestedBy {
+			node.TestedBy[i] = expand(test)
+		}
+		nodes[node.Id] = node
+	}
+	g.Nodes = nodes
+
+	for i, link := range g.Links {
+		g.Links[i].From = expand(link.From)
+		g.Links[i].To = expand(link.To)
+	}
+	g.Labels = nil
+}
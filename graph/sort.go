@@ -0,0 +1,59 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// SortByPosition sorts nodes by their position, after the nodes without a
+// position sorted by ID
+func SortByPosition(nodes []*Node) {
+	slices.SortFunc(nodes, ComparePositions)
+}
+
+// ComparePositions orders nodes by file, line and column. Nodes without a
+// position come first, ordered by ID.
+func ComparePositions(a, b *Node) int {
+	switch {
+	case a.Position == nil && b.Position == nil:
+		return cmp.Compare(a.Id, b.Id)
+	case a.Position == nil:
+		return -1
+	case b.Position == nil:
+		return 1
+	}
+	return cmp.Or(
+		cmp.Compare(a.Position.File, b.Position.File),
+		cmp.Compare(a.Position.StartLine, b.Position.StartLine),
+		cmp.Compare(a.Position.StartCol, b.Position.StartCol),
+		cmp.Compare(a.Id, b.Id),
+	)
+}
+
+// Normalize puts g into a canonical form, e.g. for committing a graph as a
+// baseline and diffing it in reviews: short IDs are expanded, links are
+// sorted by their endpoints and kind, the ID lists of nodes are sorted and
+// file names use forward slashes, also for graphs written on Windows. Nodes
+// are always written sorted by ID.
+func (g *Graph) Normalize() {
+	g.ExpandIDs()
+	slices.SortFunc(g.Links, func(a, b Link) int {
+		return cmp.Or(
+			cmp.Compare(a.From, b.From),
+			cmp.Compare(a.To, b.To),
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Weight, b.Weight),
+		)
+	})
+	for _, node := range g.Nodes {
+		for _, ids := range [][]string{node.Platforms, node.Members, node.Vulnerable, node.Vulns, node.TestedBy} {
+			slices.Sort(ids)
+		}
+		if node.Position != nil {
+			node.Position.File = strings.ReplaceAll(node.Position.File, `\`, "/")
+		}
+	}
+}
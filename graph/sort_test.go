@@ -0,0 +1,40 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSortByPosition(t *testing.T) {
+	at := func(id, file string, line, col int) *Node {
+		return &Node{Id: id, Position: &Position{File: file, StartLine: line, StartCol: col}}
+	}
+	tests := []struct {
+		name  string
+		nodes []*Node
+		want  []string
+	}{
+		{"by file, line and column", []*Node{at("a", "b.go", 1, 1), at("b", "a.go", 2, 1), at("c", "a.go", 1, 5), at("d", "a.go", 1, 2)}, []string{"d", "c", "b", "a"}},
+		{"same position by ID", []*Node{at("b", "a.go", 1, 1), at("a", "a.go", 1, 1)}, []string{"a", "b"}},
+		{"without positions by ID", []*Node{{Id: "b"}, {Id: "a"}}, []string{"a", "b"}},
+		// Mixing nodes with and without a position must still be a total
+		// order, the nodes without one come first
+		{"mixed", []*Node{{Id: "b"}, at("a", "a.go", 2, 1), at("c", "a.go", 1, 1)}, []string{"b", "c", "a"}},
+		{"mixed reversed", []*Node{at("c", "a.go", 1, 1), at("a", "a.go", 2, 1), {Id: "b"}, {Id: "d"}}, []string{"b", "d", "c", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := slices.Clone(tt.nodes)
+			SortByPosition(nodes)
+			var got []string
+			for _, node := range nodes {
+				got = append(got, node.Id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SortByPosition() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"maps"
+	"slices"
+)
+
+// Neighbors returns the sorted IDs of the nodes linking to or linked from the
+// node with the given ID, not including the node itself.
+func (g *Graph) Neighbors(id string) []string {
+	adjacent := make(map[string]bool)
+	for _, link := range g.Links {
+		switch {
+		case link.From == link.To:
+		case link.From == id:
+			adjacent[link.To] = true
+		case link.To == id:
+			adjacent[link.From] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(adjacent))
+}
+
+// neighbors returns the sorted IDs of the nodes linking to or linked from
+// every node
+func (g *Graph) neighbors() map[string][]string {
+	adjacent := make(map[string]map[string]bool)
+	add := func(from, to string) {
+		if adjacent[from] == nil {
+			adjacent[from] = make(map[string]bool)
+		}
+		adjacent[from][to] = true
+	}
+	for _, link := range g.Links {
+		if link.From != link.To {
+			add(link.From, link.To)
+			add(link.To, link.From)
+		}
+	}
+	neighbors := make(map[string][]string, len(adjacent))
+	for nodeID, ids := range adjacent {
+		neighbors[nodeID] = slices.Sorted(maps.Keys(ids))
+	}
+	return neighbors
+}
+
+// Subgraph returns the subgraph of g made of the nodes with the given IDs,
+// the nodes at most depth links away from them in either direction, and the
+// links between these nodes. A negative depth includes everything connected
+// to the nodes. Nodes are shared with g, IDs that are not part of g are
+// ignored.
+func (g *Graph) Subgraph(ids []string, depth int) *Graph {
+	sub := &Graph{Nodes: make(map[string]*Node), Labels: g.Labels, PositionStrings: g.PositionStrings}
+	var frontier []string
+	for _, nodeID := range ids {
+		if node, ok := g.Nodes[nodeID]; ok && sub.Nodes[nodeID] == nil {
+			sub.Nodes[nodeID] = node
+			frontier = append(frontier, nodeID)
+		}
+	}
+	neighbors := g.neighbors()
+	for hop := 0; len(frontier) > 0 && (depth < 0 || hop < depth); hop++ {
+		var next []string
+		for _, nodeID := range frontier {
+			for _, neighbor := range neighbors[nodeID] {
+				if node, ok := g.Nodes[neighbor]; ok && sub.Nodes[neighbor] == nil {
+					sub.Nodes[neighbor] = node
+					next = append(next, neighbor)
+				}
+			}
+		}
+		frontier = next
+	}
+	for _, link := range g.Links {
+		if sub.Nodes[link.From] != nil && sub.Nodes[link.To] != nil {
+			sub.Links = append(sub.Links, link)
+		}
+	}
+	return sub
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testGraph returns a graph of the links "from->to" given by edges, separated
+// by spaces, with a node for every ID and a reference link for every edge.
+// Nodes without links are given as a plain ID.
+func testGraph(edges string) *Graph {
+	g := &Graph{Nodes: make(map[string]*Node)}
+	for _, edge := range strings.Fields(edges) {
+		from, to, ok := strings.Cut(edge, "->")
+		g.Nodes[from] = &Node{Id: from}
+		if !ok {
+			continue
+		}
+		g.Nodes[to] = &Node{Id: to}
+		g.Links = append(g.Links, Link{From: from, To: to, Kind: LinkReference, Weight: 1})
+	}
+	return g
+}
+
+func TestNeighbors(t *testing.T) {
+	// a links to b twice and b to itself, the duplicates and self-loop must
+	// not show up
+	g := testGraph("a->b a->b b->b b->c d->b e")
+	g.Links = append(g.Links, Link{From: "a", To: "b", Kind: LinkCall, Weight: 1})
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{"a", []string{"b"}},
+		{"b", []string{"a", "c", "d"}},
+		{"c", []string{"b"}},
+		{"e", nil},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		if got := g.Neighbors(tt.id); !slices.Equal(got, tt.want) {
+			t.Errorf("Neighbors(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestSubgraph(t *testing.T) {
+	// a chain a -> b -> c -> d with e linking to b and an unconnected f
+	g := testGraph("a->b b->c c->d e->b f")
+	tests := []struct {
+		name      string
+		ids       []string
+		depth     int
+		wantNodes []string
+		wantLinks int
+	}{
+		{"depth 0", []string{"b"}, 0, []string{"b"}, 0},
+		{"depth 1", []string{"b"}, 1, []string{"a", "b", "c", "e"}, 3},
+		{"depth 1 from an end", []string{"d"}, 1, []string{"c", "d"}, 1},
+		{"depth 2", []string{"a"}, 2, []string{"a", "b", "c", "e"}, 3},
+		{"unlimited depth", []string{"a"}, -1, []string{"a", "b", "c", "d", "e"}, 4},
+		{"unconnected node", []string{"f"}, -1, []string{"f"}, 0},
+		{"several nodes", []string{"a", "f"}, 1, []string{"a", "b", "f"}, 1},
+		{"unknown node", []string{"missing"}, -1, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := g.Subgraph(tt.ids, tt.depth)
+			if got := slices.Sorted(maps.Keys(sub.Nodes)); !slices.Equal(got, tt.wantNodes) {
+				t.Errorf("nodes = %q, want %q", got, tt.wantNodes)
+			}
+			if len(sub.Links) != tt.wantLinks {
+				t.Errorf("got %d links, want %d", len(sub.Links), tt.wantLinks)
+			}
+		})
+	}
+}
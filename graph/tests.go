@@ -0,0 +1,51 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+// AddTestedBy records in every declaration of the analyzed code the test
+// functions that exercise it.
+func (g *Graph) AddTestedBy() {
+	for nodeID, tests := range g.TestsFor() {
+		g.Nodes[nodeID].TestedBy = tests
+	}
+}
+
+// TestsFor maps the IDs of the nodes outside of test files to the IDs of the
+// test functions they are reachable from, sorted by position. Reachability
+// follows the rules of deadcode, so tests exercise the implementations of the
+// interface methods they reach.
+func (g *Graph) TestsFor() map[string][]string {
+	var tests []*Node
+	for _, node := range g.Nodes {
+		if IsTestNode(node) {
+			tests = append(tests, node)
+		}
+	}
+	SortByPosition(tests)
+
+	r := g.Reachability()
+	testedBy := make(map[string][]string)
+	for _, test := range tests {
+		for nodeID := range r.From([]string{test.Id}) {
+			if node, ok := g.Nodes[nodeID]; ok && !node.Test && !node.External && node.Kind != KindPackage {
+				testedBy[nodeID] = append(testedBy[nodeID], test.Id)
+			}
+		}
+	}
+	return testedBy
+}
+
+// IsTestNode reports whether node is a function run by go test: a test,
+// benchmark, fuzz test, example or TestMain
+func IsTestNode(node *Node) bool {
+	if !node.Test || node.Kind != KindFunc || node.Parent != "" {
+		return false
+	}
+	switch node.Type {
+	case FuncTest, FuncBenchmark, FuncFuzz, FuncExample:
+		return true
+	case FuncBasic:
+		return node.LocalName == "TestMain"
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+import (
+	"path"
+	"strings"
+)
+
+// TrimPrefixes shortens the import paths starting with one of prefixes in
+// node IDs, package paths and signatures. Paths below a prefix become
+// relative to it, e.g. pkg.Foo for example.com/mod/pkg.Foo, and the prefix
+// itself is shortened to its last element, e.g. mod.Foo for example.com/mod.Foo.
+func (g *Graph) TrimPrefixes(prefixes []string) {
+	if len(prefixes) == 0 {
+		return
+	}
+	var oldnew []string
+	for _, prefix := range prefixes {
+		oldnew = append(oldnew, prefix+"/", "", prefix+".", path.Base(prefix)+".")
+	}
+	r := strings.NewReplacer(oldnew...)
+	trimPkg := func(pkgPath string) string {
+		for _, prefix := range prefixes {
+			if pkgPath == prefix {
+				return path.Base(prefix)
+			}
+			if rest, ok := strings.CutPrefix(pkgPath, prefix+"/"); ok {
+				return rest
+			}
+		}
+		return pkgPath
+	}
+
+	nodes := make(map[string]*Node, len(g.Nodes))
+	for _, node := range g.Nodes {
+		node.Id = r.Replace(node.Id)
+		node.Parent = r.Replace(node.Parent)
+		node.Pkg = trimPkg(node.Pkg)
+		node.Signature = r.Replace(node.Signature)
+		nodes[node.Id] = node
+	}
+	g.Nodes = nodes
+
+	for i, link := range g.Links {
+		g.Links[i].From = r.Replace(link.From)
+		g.Links[i].To = r.Replace(link.To)
+	}
+}
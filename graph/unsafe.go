@@ -0,0 +1,36 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package graph
+
+// UnsafeDependents returns the IDs of the nodes depending on nodes using
+// unsafe or cgo, directly or transitively, mapped to the unsafe nodes they
+// reach
+func (g *Graph) UnsafeDependents() map[string][]string {
+	dependents := make(map[string][]string)
+	for _, link := range g.Links {
+		if link.From != link.To {
+			dependents[link.To] = append(dependents[link.To], link.From)
+		}
+	}
+	reaches := make(map[string][]string)
+	for _, node := range g.Nodes {
+		if !node.Unsafe && !node.Cgo {
+			continue
+		}
+		seen := map[string]bool{node.Id: true}
+		queue := []string{node.Id}
+		for len(queue) > 0 {
+			nodeID := queue[0]
+			queue = queue[1:]
+			for _, from := range dependents[nodeID] {
+				if _, ok := g.Nodes[from]; !ok || seen[from] {
+					continue
+				}
+				seen[from] = true
+				queue = append(queue, from)
+				reaches[from] = append(reaches[from], node.Id)
+			}
+		}
+	}
+	return reaches
+}
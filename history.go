@@ -0,0 +1,169 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/internal/git"
+	"github.com/phyrog/sgope/render"
+)
+
+// Revision series selectable with history -every
+const (
+	everyTag    = "tag"
+	everyCommit = "commit"
+)
+
+// timelineEntry describes the graph of one revision of a history
+type timelineEntry struct {
+	Rev    string `json:"rev"`
+	Commit string `json:"commit"`
+	Date   string `json:"date"`
+	// File is the name of the graph file of the revision, relative to the
+	// timeline
+	File   string `json:"file"`
+	Nodes  int    `json:"nodes"`
+	Links  int    `json:"links"`
+	Cycles int    `json:"cycles"`
+	graph  *graph.Graph
+}
+
+// historyCommand analyzes a series of git revisions and writes the graph of
+// each along with a timeline summarizing how the graph evolved.
+func historyCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	since := fs.String("since", "", "Oldest git `revision` to analyze (default: the whole history)")
+	every := fs.String("every", everyTag, "Revisions to analyze ("+everyTag+", "+everyCommit+")")
+	output := fs.String("o", "history", "Output `directory` for the graphs, timeline.json and timeline.html")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope history [-since v1.0.0] [-every tag|commit] [-o history] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if opts.Rev != "" {
+			return fmt.Errorf("-rev cannot be combined with history")
+		}
+
+		revs, err := historyRevisions(*since, *every)
+		if err != nil {
+			return err
+		}
+		if len(revs) == 0 {
+			return fmt.Errorf("no revisions to analyze")
+		}
+		if err := os.MkdirAll(*output, 0o755); err != nil {
+			return err
+		}
+
+		var timeline []timelineEntry
+		for i, rev := range revs {
+			fmt.Fprintf(os.Stderr, "Analyzing %s (%d/%d)...\n", rev, i+1, len(revs))
+			info, err := git.Run("", "log", "-1", "--format=%H %cI", rev)
+			if err != nil {
+				return err
+			}
+			commit, date, _ := strings.Cut(info, " ")
+
+			revOpts := *opts
+			revOpts.Rev = commit
+			g, err := analysis.Analyze(&revOpts, fs.Args()...)
+			if err != nil {
+				return err
+			}
+			entry := timelineEntry{
+				Rev:    rev,
+				Commit: commit,
+				Date:   date,
+				File:   fmt.Sprintf("%04d-%s.json", i+1, commit[:min(12, len(commit))]),
+				Nodes:  len(g.Nodes),
+				Links:  len(g.Links),
+				Cycles: len(g.OwnerGraph().Cycles()),
+				graph:  g,
+			}
+			jsonData, err := json.Marshal(g)
+			if err != nil {
+				return fmt.Errorf("JSON marshaling error: %w", err)
+			}
+			if err := render.WriteOutput(filepath.Join(*output, entry.File), "json", jsonData); err != nil {
+				return err
+			}
+			timeline = append(timeline, entry)
+		}
+
+		jsonData, err := json.Marshal(struct {
+			Revisions []timelineEntry `json:"revisions"`
+		}{timeline})
+		if err != nil {
+			return fmt.Errorf("JSON marshaling error: %w", err)
+		}
+		if err := render.WriteOutput(filepath.Join(*output, "timeline.json"), "json", jsonData); err != nil {
+			return err
+		}
+
+		// The HTML timeline embeds every graph, so the viz can switch between
+		// revisions without loading files
+		type vizEntry struct {
+			Rev   string       `json:"rev"`
+			Date  string       `json:"date"`
+			Graph *graph.Graph `json:"graph"`
+		}
+		var entries []vizEntry
+		for _, entry := range timeline {
+			entries = append(entries, vizEntry{entry.Rev, entry.Date, entry.graph})
+		}
+		jsonData, err = json.Marshal(struct {
+			Timeline []vizEntry `json:"timeline"`
+		}{entries})
+		if err != nil {
+			return fmt.Errorf("JSON marshaling error: %w", err)
+		}
+		if err := render.WriteOutput(filepath.Join(*output, "timeline.html"), "html", jsonData); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d revisions to %s\n", len(timeline), *output)
+		return nil
+	}
+}
+
+// historyRevisions lists the revisions to analyze, oldest first: the tags
+// reachable from HEAD, or the first-parent commits leading to HEAD, starting
+// at since if given.
+func historyRevisions(since, every string) ([]string, error) {
+	var args []string
+	switch every {
+	case everyTag:
+		args = []string{"tag", "--merged", "HEAD", "--sort=creatordate"}
+		if since != "" {
+			args = append(args, "--contains", since)
+		}
+	case everyCommit:
+		args = []string{"rev-list", "--reverse", "--first-parent", "HEAD"}
+		if since != "" {
+			args = []string{"rev-list", "--reverse", "--first-parent", since + "..HEAD"}
+		}
+	default:
+		return nil, fmt.Errorf("unknown revision series %q (available: %s, %s)", every, everyTag, everyCommit)
+	}
+	out, err := git.Run("", args...)
+	if err != nil {
+		return nil, err
+	}
+	revs := strings.Fields(out)
+	if every == everyCommit && since != "" {
+		revs = append([]string{since}, revs...)
+	}
+	return revs, nil
+}
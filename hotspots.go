@@ -0,0 +1,122 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// hotspotThresholds are the limits above which a type or package is reported
+// as a hot spot
+type hotspotThresholds struct {
+	methods, fanIn, fanOut     int
+	decls, pkgFanIn, pkgFanOut int
+}
+
+// hotspot is a type or package exceeding at least one threshold
+type hotspot struct {
+	node     *graph.Node
+	exceeded []string
+}
+
+// hotspotsCommand flags god objects: types and packages with extreme numbers
+// of methods or declarations, dependents and dependencies.
+func hotspotsCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("hotspots", flag.ExitOnError)
+	var t hotspotThresholds
+	fs.IntVar(&t.methods, "methods", 20, "Maximum number of methods of a type")
+	fs.IntVar(&t.fanIn, "fan-in", 30, "Maximum number of declarations depending on a type or its members")
+	fs.IntVar(&t.fanOut, "fan-out", 30, "Maximum number of declarations a type or its members depend on")
+	fs.IntVar(&t.decls, "decls", 100, "Maximum number of package-level declarations of a package")
+	fs.IntVar(&t.pkgFanIn, "pkg-fan-in", 20, "Maximum number of packages depending on a package")
+	fs.IntVar(&t.pkgFanOut, "pkg-fan-out", 20, "Maximum number of packages a package depends on")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope hotspots [-methods 20] [-fan-in 30] [-fan-out 30] [-decls 100] [-pkg-fan-in 20] [-pkg-fan-out 20] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		for _, h := range hotspots(g, t) {
+			if h.node.Position != nil {
+				fmt.Printf("%s: ", nodePosition(h.node))
+			}
+			fmt.Printf("%s %s: %s\n", h.node.Kind, h.node.Id, strings.Join(h.exceeded, ", "))
+		}
+		return nil
+	}
+}
+
+// hotspots returns the types and packages of g exceeding the thresholds, the
+// ones exceeding the most thresholds first. The fan-in and fan-out of a type
+// count the distinct declarations linking to or linked from the type, its
+// fields and its methods.
+func hotspots(g *graph.Graph, t hotspotThresholds) []hotspot {
+	types := g.DeclarationGraph()
+	fan := types.FanCounts()
+
+	methods := make(map[string]int)
+	decls := make(map[string]int)
+	for _, node := range g.Nodes {
+		if node.Type == graph.FuncMethod {
+			methods[node.Parent]++
+		}
+		if node.Parent == "" && node.Kind != graph.KindPackage && node.Kind != graph.KindFile && node.Kind != graph.KindComponent {
+			decls[node.Pkg]++
+		}
+	}
+
+	check := func(exceeded []string, what string, value, limit int) []string {
+		if value > limit {
+			exceeded = append(exceeded, fmt.Sprintf("%d %s (> %d)", value, what, limit))
+		}
+		return exceeded
+	}
+
+	var hotspots []hotspot
+	packages := make(map[string]*graph.Node)
+	for _, node := range types.Nodes {
+		if node.External || node.Test {
+			continue
+		}
+		if node.Kind == graph.KindType {
+			var exceeded []string
+			exceeded = check(exceeded, "methods", methods[node.Id], t.methods)
+			exceeded = check(exceeded, "dependents", fan.In[node.Id], t.fanIn)
+			exceeded = check(exceeded, "dependencies", fan.Out[node.Id], t.fanOut)
+			if len(exceeded) > 0 {
+				hotspots = append(hotspots, hotspot{node, exceeded})
+			}
+		}
+		if _, ok := packages[node.Pkg]; !ok {
+			packages[node.Pkg] = &graph.Node{Kind: graph.KindPackage, Id: node.Pkg, Pkg: node.Pkg}
+			var exceeded []string
+			exceeded = check(exceeded, "declarations", decls[node.Pkg], t.decls)
+			exceeded = check(exceeded, "dependent packages", fan.PkgIn[node.Pkg], t.pkgFanIn)
+			exceeded = check(exceeded, "package dependencies", fan.PkgOut[node.Pkg], t.pkgFanOut)
+			if len(exceeded) > 0 {
+				hotspots = append(hotspots, hotspot{packages[node.Pkg], exceeded})
+			}
+		}
+	}
+	slices.SortFunc(hotspots, func(a, b hotspot) int {
+		return cmp.Or(cmp.Compare(len(b.exceeded), len(a.exceeded)), cmp.Compare(a.node.Id, b.node.Id))
+	})
+	return hotspots
+}
@@ -0,0 +1,204 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// impactReport lists the symbols declared in changed files and the symbols
+// and tests depending on them
+type impactReport struct {
+	Changed  []string `json:"changed"`
+	Affected []string `json:"affected"`
+	Tests    []string `json:"tests"`
+}
+
+// impactCommand maps changed files to the symbols declared in them and lists
+// all symbols and tests that transitively depend on those symbols.
+func impactCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("impact", flag.ExitOnError)
+	files := fs.String("files", "", "Comma-separated changed `files` (default: read file names from stdin, e.g. from git diff --name-only)")
+	jsonMode := fs.Bool("json", false, "Output the changed, affected and test symbols as JSON")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope impact [-files a.go,b.go] [-json] <package-path> [<package-path>...]")
+		fmt.Fprintln(fs.Output(), "  Omit -files to read changed file names from stdin, one per line")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		var changed []string
+		if *files != "" {
+			for file := range strings.SplitSeq(*files, ",") {
+				if file = strings.TrimSpace(file); file != "" {
+					changed = append(changed, file)
+				}
+			}
+		} else {
+			fmt.Fprintln(os.Stderr, "Reading changed files from stdin...")
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				if file := strings.TrimSpace(scanner.Text()); file != "" {
+					changed = append(changed, file)
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return fmt.Errorf("Failed to read changed files from stdin: %w", err)
+			}
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		report := impact(g, changed)
+
+		if *jsonMode {
+			jsonData, err := json.Marshal(report)
+			if err != nil {
+				return fmt.Errorf("JSON marshaling error: %w", err)
+			}
+			return render.WriteJSON(os.Stdout, jsonData)
+		}
+		for _, section := range []struct {
+			title string
+			ids   []string
+		}{
+			{"Changed", report.Changed},
+			{"Affected", report.Affected},
+			{"Tests", report.Tests},
+		} {
+			fmt.Printf("%s (%d):\n", section.title, len(section.ids))
+			for _, nodeID := range section.ids {
+				fmt.Printf("\t%s: %s\n", nodePosition(g.Nodes[nodeID]), nodeID)
+			}
+		}
+		return nil
+	}
+}
+
+// impact returns the nodes declared in the given files and the nodes that
+// depend on them. A node is affected if it links to a changed or affected
+// node, if one of its fields or closures is affected, or if it is an
+// interface method implemented by an affected method. Files match node
+// positions by path suffix, so paths relative to the repository root match
+// positions relative to a module root below it. Test functions that are
+// changed or depend on the changes are listed separately.
+func impact(g *graph.Graph, files []string) impactReport {
+	changedFiles := make([]string, 0, len(files))
+	for _, file := range files {
+		changedFiles = append(changedFiles, filepath.ToSlash(filepath.Clean(file)))
+	}
+	inChangedFile := func(node *graph.Node) bool {
+		if node.Position == nil || node.External {
+			return false
+		}
+		file := filepath.ToSlash(node.Position.File)
+		for _, changed := range changedFiles {
+			if file == changed || strings.HasSuffix(changed, "/"+file) {
+				return true
+			}
+		}
+		return false
+	}
+
+	dependents := make(map[string][]string)
+	interfaces := make(map[string][]string)
+	for _, link := range g.Links {
+		dependents[link.To] = append(dependents[link.To], link.From)
+		if link.Kind == graph.LinkImplements {
+			interfaces[link.From] = append(interfaces[link.From], link.To)
+		}
+	}
+	methods := make(map[string]map[string]string)
+	for _, node := range g.Nodes {
+		if node.Type == graph.FuncMethod {
+			if methods[node.Parent] == nil {
+				methods[node.Parent] = make(map[string]string)
+			}
+			methods[node.Parent][graph.MethodName(node)] = node.Id
+		}
+	}
+
+	seen := make(map[string]bool)
+	var queue []string
+	mark := func(nodeID string) {
+		if !seen[nodeID] {
+			seen[nodeID] = true
+			queue = append(queue, nodeID)
+		}
+	}
+	for _, node := range g.Nodes {
+		if inChangedFile(node) {
+			mark(node.Id)
+		}
+	}
+	changed := make(map[string]bool, len(seen))
+	for nodeID := range seen {
+		changed[nodeID] = true
+	}
+
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+		for _, from := range dependents[nodeID] {
+			mark(from)
+		}
+		node := g.Nodes[nodeID]
+		if node == nil {
+			continue
+		}
+		switch node.Type {
+		case graph.VarField, graph.FuncClosure:
+			if node.Parent != "" {
+				mark(node.Parent)
+			}
+		case graph.FuncMethod:
+			for _, iface := range interfaces[node.Parent] {
+				if ifaceMethod, ok := methods[iface][graph.MethodName(node)]; ok {
+					mark(ifaceMethod)
+				}
+			}
+		}
+	}
+
+	var changedNodes, affected, tests []*graph.Node
+	for nodeID := range seen {
+		node := g.Nodes[nodeID]
+		if node == nil || node.External || node.Kind == graph.KindPackage || node.Kind == graph.KindFile {
+			continue
+		}
+		switch {
+		case graph.IsTestNode(node):
+			tests = append(tests, node)
+		case changed[nodeID]:
+			changedNodes = append(changedNodes, node)
+		default:
+			affected = append(affected, node)
+		}
+	}
+	ids := func(nodes []*graph.Node) []string {
+		graph.SortByPosition(nodes)
+		nodeIDs := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			nodeIDs = append(nodeIDs, node.Id)
+		}
+		return nodeIDs
+	}
+	return impactReport{ids(changedNodes), ids(affected), ids(tests)}
+}
@@ -0,0 +1,181 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// interfaceSuggestion is a minimal interface covering the methods of a
+// concrete type used by a set of callers
+type interfaceSuggestion struct {
+	typeID  string
+	methods []*graph.Node
+	callers []string
+}
+
+// interfacesCommand suggests minimal interfaces for the concrete types whose
+// callers only use a subset of their methods, following "accept interfaces,
+// return structs".
+func interfacesCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("interfaces", flag.ExitOnError)
+	minCallers := fs.Int("min-callers", 1, "Minimum number of callers using the same methods to suggest an interface")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope interfaces [-min-callers 1] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range interfaceSuggestions(g) {
+			if len(s.callers) < *minCallers {
+				continue
+			}
+			fmt.Printf("// %s uses only these methods of %s\n", strings.Join(s.callers, ", "), s.typeID)
+			fmt.Printf("type %s interface {\n", s.name(g))
+			for _, method := range s.methods {
+				fmt.Printf("\t%s\n", interfaceMethod(method))
+			}
+			fmt.Print("}\n\n")
+		}
+		return nil
+	}
+}
+
+// interfaceSuggestions groups the declarations using methods of a concrete
+// type by the set of methods they use, for every set that is a strict subset
+// of the type's methods. Declarations accessing fields of the type are left
+// out, since they cannot use an interface instead.
+func interfaceSuggestions(g *graph.Graph) []interfaceSuggestion {
+	methodCount := make(map[string]int)
+	for _, node := range g.Nodes {
+		if node.Type == graph.FuncMethod {
+			methodCount[node.Parent]++
+		}
+	}
+
+	// uses maps types to their callers to the methods they use
+	uses := make(map[string]map[string]map[string]bool)
+	fieldUsers := make(map[string]map[string]bool)
+	for _, link := range g.Links {
+		switch link.Kind {
+		case graph.LinkReference, graph.LinkCall, graph.LinkValue, graph.LinkGo, graph.LinkDefer:
+		default:
+			continue
+		}
+		from, to := g.Nodes[link.From], g.Nodes[link.To]
+		if from == nil || to == nil || from.Test || to.External {
+			continue
+		}
+		typ, ok := g.Nodes[to.Parent]
+		if !ok || typ.Kind != graph.KindType || typ.Type == graph.TypeInterface {
+			continue
+		}
+		caller := g.Owner(from)
+		if caller.Type == graph.FuncMethod && caller.Parent == typ.Id {
+			continue
+		}
+		switch to.Type {
+		case graph.FuncMethod:
+			if uses[typ.Id] == nil {
+				uses[typ.Id] = make(map[string]map[string]bool)
+			}
+			if uses[typ.Id][caller.Id] == nil {
+				uses[typ.Id][caller.Id] = make(map[string]bool)
+			}
+			uses[typ.Id][caller.Id][to.Id] = true
+		case graph.VarField:
+			if fieldUsers[typ.Id] == nil {
+				fieldUsers[typ.Id] = make(map[string]bool)
+			}
+			fieldUsers[typ.Id][caller.Id] = true
+		}
+	}
+
+	var suggestions []interfaceSuggestion
+	for typeID, callers := range uses {
+		bySet := make(map[string]*interfaceSuggestion)
+		for caller, methods := range callers {
+			if fieldUsers[typeID][caller] || len(methods) == methodCount[typeID] {
+				continue
+			}
+			var ids []string
+			for methodID := range methods {
+				ids = append(ids, methodID)
+			}
+			slices.Sort(ids)
+			key := strings.Join(ids, "\x00")
+			if bySet[key] == nil {
+				s := &interfaceSuggestion{typeID: typeID}
+				for _, methodID := range ids {
+					s.methods = append(s.methods, g.Nodes[methodID])
+				}
+				bySet[key] = s
+			}
+			bySet[key].callers = append(bySet[key].callers, caller)
+		}
+		for _, s := range bySet {
+			slices.Sort(s.callers)
+			suggestions = append(suggestions, *s)
+		}
+	}
+	slices.SortFunc(suggestions, func(a, b interfaceSuggestion) int {
+		return cmp.Or(
+			cmp.Compare(a.typeID, b.typeID),
+			cmp.Compare(len(a.methods), len(b.methods)),
+			cmp.Compare(a.callers[0], b.callers[0]),
+		)
+	})
+	return suggestions
+}
+
+// name proposes an unexported interface name: the method names followed by
+// "er" for up to two methods, e.g. readCloser, and the type name followed by
+// "API" otherwise.
+func (s interfaceSuggestion) name(g *graph.Graph) string {
+	var name string
+	if len(s.methods) <= 2 {
+		for _, method := range s.methods {
+			name += graph.MethodName(method)
+		}
+		name += "er"
+	} else {
+		name = g.Nodes[s.typeID].LocalName + "API"
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// interfaceMethod returns the interface method specification for a method
+// node, e.g. Area() float64 for func (c Circle) Area() float64. Types are
+// qualified relative to the method's package.
+func interfaceMethod(method *graph.Node) string {
+	sig, ok := strings.CutPrefix(method.Signature, "func (")
+	if !ok {
+		return graph.MethodName(method) + "()"
+	}
+	// Skip the receiver, which cannot contain parentheses
+	if i := strings.Index(sig, ") "); i >= 0 {
+		return sig[i+2:]
+	}
+	return graph.MethodName(method) + "()"
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+// Package git runs git commands for analyzing revisions and history.
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Run runs a git command in dir and returns its trimmed output
+func Run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,207 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+	"gopkg.in/yaml.v3"
+)
+
+// ruleSet is the content of a rules file for sgope lint
+type ruleSet struct {
+	// Layers maps layer names to the package patterns they consist of
+	Layers map[string][]string `yaml:"layers"`
+	// Order lists layers from top to bottom. Every layer must not depend on
+	// the layers above it, which is added to the rules.
+	Order []string `yaml:"order"`
+	Rules []rule   `yaml:"rules"`
+}
+
+// rule restricts the dependencies of the packages of a layer. Layers can be
+// given by name or as a package pattern.
+type rule struct {
+	From string `yaml:"from"`
+	// Deny lists layers the packages must not depend on
+	Deny []string `yaml:"deny"`
+	// Require lists layers the packages must depend on
+	Require []string `yaml:"require"`
+	// Allow, if set, lists the only other layers the packages may depend
+	// on. Dependencies on external packages not part of any layer are
+	// always allowed.
+	Allow []string `yaml:"allow"`
+}
+
+// violation is a broken rule, with the nodes of the link breaking it if any
+type violation struct {
+	node, target *graph.Node
+	message      string
+}
+
+// lintCommand checks the dependencies of the analyzed packages against the
+// architecture rules of a rules file.
+func lintCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	rulesFile := fs.String("rules", "sgope.yaml", "Rules `file` with layers and their allowed dependencies, if "+configFile+" has no lint section")
+	jsonMode := fs.Bool("json", false, "Output the violations as JSON findings")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope lint [-rules sgope.yaml] [-json] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		// The rules of the project configuration apply unless -rules is given
+		rulesSet := false
+		fs.Visit(func(f *flag.Flag) { rulesSet = rulesSet || f.Name == "rules" })
+		config, _ := loadConfig()
+		rules := config.lintRules()
+		if rules == nil || rulesSet {
+			var err error
+			if rules, err = loadRules(*rulesFile); err != nil {
+				return err
+			}
+		}
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+
+		var findings []finding
+		for _, v := range lint(g, rules) {
+			f := finding{Check: "lint", Message: v.message}
+			if v.node != nil {
+				f.Node, f.Position = v.node.Id, v.node.Position
+				if !*jsonMode {
+					fmt.Printf("%s: ", nodePosition(v.node))
+				}
+			}
+			if v.target != nil {
+				f.Related = []string{v.target.Id}
+			}
+			if !*jsonMode {
+				fmt.Println(v.message)
+			}
+			findings = append(findings, f)
+		}
+		return reportFindings(os.Stdout, "lint", exitViolations, findings, *jsonMode)
+	}
+}
+
+// loadRules reads and checks a rules file
+func loadRules(path string) (*ruleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules ruleSet
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := rules.init(path); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// init checks the rules read from path and adds the rules implied by the
+// order of the layers
+func (rs *ruleSet) init(path string) error {
+	for i, r := range rs.Rules {
+		if r.From == "" {
+			return fmt.Errorf("%s: rule %d has no from", path, i+1)
+		}
+	}
+	for i, layer := range rs.Order {
+		if i > 0 {
+			rs.Rules = append(rs.Rules, rule{From: layer, Deny: rs.Order[:i]})
+		}
+	}
+	return nil
+}
+
+// patterns returns the package patterns of a layer name, or the name itself
+// if it is not a layer
+func (rs *ruleSet) patterns(layer string) []string {
+	if patterns, ok := rs.Layers[layer]; ok {
+		return patterns
+	}
+	return []string{layer}
+}
+
+// matches reports whether pkg belongs to layer
+func (rs *ruleSet) matches(layer, pkg string) bool {
+	return slices.ContainsFunc(rs.patterns(layer), func(pattern string) bool {
+		return matchPackagePattern(pattern, pkg)
+	})
+}
+
+// matchPackagePattern reports whether pkg matches pattern, which is a
+// package path optionally ending in /... to include all packages below it
+func matchPackagePattern(pattern, pkg string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+	return pkg == pattern
+}
+
+// lint returns the violations of rules by the links of g, sorted by
+// position. Required dependencies that are missing are reported without a
+// position.
+func lint(g *graph.Graph, rules *ruleSet) []violation {
+	var violations, missing []violation
+	for _, r := range rules.Rules {
+		found := make(map[string]bool)
+		for _, link := range g.Links {
+			from, to := g.Nodes[link.From], g.Nodes[link.To]
+			if from == nil || to == nil || from.Pkg == to.Pkg || !rules.matches(r.From, from.Pkg) {
+				continue
+			}
+			for _, layer := range r.Require {
+				if rules.matches(layer, to.Pkg) {
+					found[layer] = true
+				}
+			}
+			for _, layer := range r.Deny {
+				if rules.matches(layer, to.Pkg) {
+					violations = append(violations, violation{from, to, fmt.Sprintf("%s -> %s: %s must not depend on %s", from.Id, to.Id, r.From, layer)})
+				}
+			}
+			if r.Allow != nil && !rules.matches(r.From, to.Pkg) && !slices.ContainsFunc(r.Allow, func(layer string) bool {
+				return rules.matches(layer, to.Pkg)
+			}) && !(to.External && !rules.inAnyLayer(to.Pkg)) {
+				violations = append(violations, violation{from, to, fmt.Sprintf("%s -> %s: %s may only depend on %s", from.Id, to.Id, r.From, strings.Join(r.Allow, ", "))})
+			}
+		}
+		for _, layer := range r.Require {
+			if !found[layer] {
+				missing = append(missing, violation{nil, nil, fmt.Sprintf("%s must depend on %s, but does not", r.From, layer)})
+			}
+		}
+	}
+	slices.SortFunc(violations, func(a, b violation) int {
+		return cmp.Or(graph.ComparePositions(a.node, b.node), strings.Compare(a.message, b.message))
+	})
+	return append(violations, missing...)
+}
+
+// inAnyLayer reports whether pkg belongs to one of the named layers
+func (rs *ruleSet) inAnyLayer(pkg string) bool {
+	for layer := range rs.Layers {
+		if rs.matches(layer, pkg) {
+			return true
+		}
+	}
+	return false
+}
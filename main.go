@@ -3,85 +3,526 @@
 package main
 
 import (
-	_ "embed"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+	"gopkg.in/yaml.v3"
 )
 
+// command is a subcommand of sgope, whose flags are parsed from the
+// arguments following the command name
+type command struct {
+	// flags registers the flags of the command on a new flag set and returns
+	// it with the function running the command once they are parsed
+	flags func() (*flag.FlagSet, func() error)
+	// summary describes the command in the list of commands
+	summary string
+}
+
+// commands maps subcommand names to their entry points
+var commands = map[string]command{
+	"analyze":        {analyzeCommand, "Write the graph of packages as JSON or HTML"},
+	"completion":     {completionCommand, "Print a shell completion script for bash, zsh or fish"},
+	"communities":    {communitiesCommand, "Cluster declarations and report clusters spanning packages"},
+	"cycles":         {cyclesCommand, "Report dependency cycles between symbols or packages"},
+	"daemon":         {daemonCommand, "Answer analysis requests on a unix socket from cached packages"},
+	"deadcode":       {deadcodeCommand, "List declarations unreachable from main, exported symbols or tests"},
+	"diff":           {diffCommand, "Compare two graph files"},
+	"examples":       {examplesCommand, "Report exported symbols without example functions"},
+	"export":         {exportCommand, "Write a standalone HTML visualization"},
+	"export-html":    {exportCommand, "Same as export"},
+	"flows":          {flowsCommand, "Report dependency paths from source to sink symbols"},
+	"fmt":            {fmtCommand, "Write graph files in canonical form"},
+	"history":        {historyCommand, "Analyze a series of git revisions"},
+	"hotspots":       {hotspotsCommand, "Flag types and packages with extreme sizes or coupling"},
+	"impact":         {impactCommand, "List the symbols and tests affected by changed files"},
+	"interfaces":     {interfacesCommand, "Suggest minimal interfaces for concrete types"},
+	"lint":           {lintCommand, "Check dependencies against architecture rules"},
+	"merge":          {mergeCommand, "Combine graph files"},
+	"metrics":        {metricsCommand, "Report coupling, instability and abstractness of packages"},
+	"prune":          {pruneCommand, "Filter and collapse a graph file"},
+	"query":          {queryCommand, "Show a symbol with its dependencies and dependents"},
+	"serve":          {serveCommand, "Serve the visualization of packages or a graph"},
+	"stats":          {statsCommand, "Summarize the size and shape of the graph"},
+	"tests-for":      {testsForCommand, "List the tests exercising a symbol"},
+	"top":            {topCommand, "List the symbols or packages with the highest fan-in and fan-out"},
+	"untested":       {untestedCommand, "List exported functions no test exercises"},
+	"unsafe":         {unsafeCommand, "List declarations using unsafe or cgo"},
+	"unused-exports": {unusedExportsCommand, "List exported symbols unused outside their package"},
+	"validate":       {validateCommand, "Check the structure of a graph file"},
+	"vulnpaths":      {vulnpathsCommand, "Print dependency chains to vulnerable symbols"},
+	"watch":          {watchCommand, "Serve the visualization and analyze again when files change"},
+	"why":            {whyCommand, "Print the dependency paths from one symbol to another"},
+}
+
 func main() {
-	jsonMode := flag.Bool("json", false, "Output JSON to stdout instead of serving visualization")
-	port := flag.String("port", "8080", "Port for visualization")
-	flag.Parse()
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	name, args := os.Args[1], os.Args[2:]
+	switch {
+	case name == "help" || name == "-h" || name == "-help" || name == "--help":
+		if len(args) == 0 {
+			usage(os.Stdout)
+			return
+		}
+		cmd, ok := commands[args[0]]
+		if !ok {
+			log.Fatalf("Unknown command %q, run 'sgope help' for a list of commands", args[0])
+		}
+		run(cmd, []string{"-h"})
+	case name == "__complete":
+		// Called by the scripts of sgope completion
+		runComplete(args)
+	case commands[name].flags != nil:
+		run(commands[name], args)
+	case strings.HasPrefix(name, "-") || strings.ContainsAny(name, "./"):
+		// Flags and package paths without a command, as in earlier versions
+		if err := runRoot(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("Unknown command %q, run 'sgope help' for a list of commands", name)
+	}
+}
+
+// run runs cmd with the flags in args and exits with the status of the
+// findings it reports, if any
+func run(cmd command, args []string) {
+	fs, runCmd := cmd.flags()
+	parseFlags(fs, args)
+	if err := runCmd(); err != nil {
+		var findings *findingsError
+		if errors.As(err, &findings) {
+			log.Print(err)
+			os.Exit(findings.code)
+		}
+		log.Fatal(err)
+	}
+}
 
-	args := flag.Args()
+// usage prints the list of commands to w
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: sgope <command> [flags] [<package-path>...]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		fmt.Fprintf(w, "  %-16s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'sgope help <command>' for the flags of a command.")
+	fmt.Fprintln(w, "Use '...' suffix for recursive package discovery (e.g., ./pkg/...).")
+}
 
-	var jsonData []byte
-	var err error
+// runRoot runs sgope without a command like earlier versions: it serves the
+// visualization, or writes the graph with -format or -json.
+func runRoot(args []string) error {
+	fs := flag.NewFlagSet("sgope", flag.ExitOnError)
+	jsonMode := fs.Bool("json", false, "Output JSON to stdout instead of serving visualization (same as -format json)")
+	format := fs.String("format", "", "Output format instead of serving visualization ("+strings.Join(render.FormatNames(), ", ")+")")
+	output := fs.String("o", "-", "Output file for -format, '-' for stdout")
+	server := addServeFlags(fs)
+	opts := addAnalyzeFlags(fs)
+	maxNodes := addViewFlags(fs, opts)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope [-json] [-format json|html] [-o file] [-listen localhost:8080] <package-path> [<package-path>...]")
+		fmt.Fprintln(fs.Output(), "  Same as sgope serve, or sgope analyze with -format or -json")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
 
-	// If no args provided and not in JSON mode, read from stdin
-	if len(args) == 0 && !*jsonMode {
-		fmt.Fprintln(os.Stderr, "Reading graph data from stdin...")
-		jsonData, err = io.ReadAll(os.Stdin)
-		if err != nil {
-			log.Fatalf("Failed to read JSON from stdin: %v", err)
-		}
-	} else if len(args) == 0 {
-		fmt.Println("Usage: sgope [-json] [-port 8080] <package-path> [<package-path>...] ")
-		fmt.Println("  Use '...' suffix for recursive package discovery (e.g., ./pkg/...)")
-		fmt.Println("  Omit package paths to read graph data from stdin and serve visualization")
-		os.Exit(1)
-	} else {
-		graph, err := analyzePackages(args...)
+	if *jsonMode {
+		*format = "json"
+	}
+	if fs.NArg() == 0 && *format == "json" && !opts.Workspace {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		opts.MaxNodes = *maxNodes
+		return serveGraph(server, opts, fs.Args())
+	}
+	if *format != "json" {
+		opts.MaxNodes = *maxNodes
+	}
+	return writeGraph(*output, *format, opts, fs.Args())
+}
+
+// analyzeCommand analyzes packages and writes the graph in one of the output
+// formats.
+func analyzeCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	format := fs.String("format", "json", "Output format ("+strings.Join(render.FormatNames(), ", ")+")")
+	output := fs.String("o", "-", "Output file, '-' for stdout")
+	opts := addAnalyzeFlags(fs)
+	maxNodes := addViewFlags(fs, opts)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope analyze [-format json|html] [-o file] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if *format != "json" {
+			opts.MaxNodes = *maxNodes
+		}
+		return writeGraph(*output, *format, opts, fs.Args())
+	}
+}
+
+// serveCommand serves the visualization of the graph of packages, or of a
+// graph read from stdin.
+func serveCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	server := addServeFlags(fs)
+	opts := addAnalyzeFlags(fs)
+	maxNodes := addViewFlags(fs, opts)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope serve [-listen localhost:8080] [<package-path>...]")
+		fmt.Fprintln(fs.Output(), "  Omit package paths to read graph data from stdin")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		opts.MaxNodes = *maxNodes
+
+		return serveGraph(server, opts, fs.Args())
+	}
+}
+
+// writeGraph writes the graph of paths to output in the given format
+func writeGraph(output, format string, opts *analysis.Options, paths []string) error {
+	if _, ok := render.Formats[format]; !ok {
+		return fmt.Errorf("unknown output format %q (available: %s)", format, strings.Join(render.FormatNames(), ", "))
+	}
+	jsonData, err := loadGraphJSON(opts, paths)
+	if err != nil {
+		return err
+	}
+	return render.WriteOutput(output, format, jsonData)
+}
+
+// serveGraph serves the visualization of the graph of paths
+func serveGraph(server *serveOptions, opts *analysis.Options, paths []string) error {
+	if err := server.check(); err != nil {
+		return err
+	}
+	jsonData, err := loadGraphJSON(opts, paths)
+	if err != nil {
+		return err
+	}
+	return server.serve(render.Handler(jsonData))
+}
+
+// defaultMaxNodes is the number of nodes above which the visualization
+// collapses nodes into summary nodes, as more slow down browsers to a halt
+const defaultMaxNodes = 5000
+
+// addViewFlags registers the flags thinning out the graph for viewing on fs.
+// The returned limit of nodes only applies to the visualization.
+func addViewFlags(fs *flag.FlagSet, opts *analysis.Options) (maxNodes *int) {
+	fs.IntVar(&opts.MinWeight, "min-weight", 0, "Leave out links with a weight below `N`, e.g. references made fewer than N times")
+	fs.IntVar(&opts.TopLinks, "top-links", 0, "Only keep the `K` heaviest links of every node, in both directions, 0 for all")
+	return fs.Int("max-nodes", defaultMaxNodes, "Collapse leaves and then whole packages into summary nodes when the visualization would show more than `N` nodes, 0 for no limit")
+}
+
+// addAnalyzeFlags registers the flags controlling package analysis on fs
+func addAnalyzeFlags(fs *flag.FlagSet) *analysis.Options {
+	var opts analysis.Options
+	fs.StringVar(&opts.CallGraph, "callgraph", analysis.CallGraphNone, "Add call edges computed by an SSA call graph algorithm ("+strings.Join(analysis.CallGraphModes, ", ")+")")
+	fs.BoolVar(&opts.Closures, "closures", false, "Emit function literals as child nodes of their enclosing declaration")
+	fs.BoolVar(&opts.Instances, "instances", false, "Emit nodes for concrete instantiations of generic functions and types")
+	fs.BoolVar(&opts.IncludeStd, "include-std", false, "Include referenced standard library symbols as leaf nodes")
+	fs.StringVar(&opts.Tags, "tags", "", "Comma-separated list of build tags to consider satisfied, as in go build -tags")
+	fs.StringVar(&opts.GOOS, "goos", "", "Target operating system to analyze for (default $GOOS)")
+	fs.StringVar(&opts.GOARCH, "goarch", "", "Target architecture to analyze for (default $GOARCH)")
+	fs.Var((*listFlag)(&opts.Env), "env", "Set an environment variable for the go command as `KEY=VALUE`, e.g. CGO_ENABLED=0, may be repeated")
+	fs.StringVar(&opts.Platforms, "platforms", "", "Comma-separated GOOS/GOARCH pairs to analyze, producing the union of the graphs with nodes annotated by platform")
+	fs.BoolVar(&opts.Workspace, "workspace", false, "Analyze all modules of the active go.work file")
+	fs.BoolVar(&opts.ExportedOnly, "exported", false, "Only include exported declarations, i.e. the public API")
+	fs.BoolVar(&opts.ExcludeGenerated, "exclude-generated", false, "Leave out declarations in generated files, e.g. protobuf code or mocks")
+	fs.Var((*listFlag)(&opts.Focus), "focus", "Only include the declaration with the given `ID` and its neighborhood up to -depth links away, may be repeated")
+	fs.IntVar(&opts.FocusDepth, "depth", 1, "Number of links to follow from the -focus declarations in either direction, -1 for everything connected to them")
+	fs.Func("include", "Only include the declarations whose ID or package path matches a regular `expression`", func(s string) (err error) {
+		opts.Include, err = regexp.Compile(s)
+		return err
+	})
+	fs.Func("exclude", "Leave out the declarations whose ID or package path matches a regular `expression`, e.g. /vendor/|_mock", func(s string) (err error) {
+		opts.Exclude, err = regexp.Compile(s)
+		return err
+	})
+	fs.BoolVar(&opts.ExcludeTests, "exclude-tests", false, "Leave out test files, analyzing only the code that is built")
+	fs.Var((*classifyFlag)(&opts.Classifiers), "classify", "Assign nodes to groups by the rules in a YAML `file`, e.g. a group for all mocks, may be repeated")
+	fs.BoolVar(&opts.FileNodes, "file-nodes", false, "Emit a node for every source file with contains edges to its declarations")
+	fs.BoolVar(&opts.Hierarchy, "hierarchy", false, "Emit nodes for all packages and their parent directories with contains edges to their contents")
+	fs.BoolVar(&opts.UnsafeOnly, "unsafe-only", false, "Only include declarations using unsafe or cgo and the declarations depending on them")
+	fs.BoolVar(&opts.BestEffort, "best-effort", false, "Emit declarations of packages that fail to load from their syntax alone")
+	fs.BoolVar(&opts.PositionStrings, "position-string", false, "Emit node positions as \"file:line:col-line:col\" strings like earlier versions")
+	fs.Var((*trimPrefixFlag)(&opts.TrimPrefix), "trim-prefix", "Shorten import paths starting with `prefix` in node IDs, e.g. pkg.Foo instead of example.com/mod/pkg.Foo (-trim-prefix trims the analyzed module paths)")
+	fs.BoolVar(&opts.Condense, "condense", false, "Collapse every dependency cycle into a single component node, making the graph a DAG")
+	fs.BoolVar(&opts.Reduce, "reduce", false, "Remove links implied by transitivity, keeping which nodes depend on which")
+	fs.Var((*listFlag)(&opts.Plugins), "plugin", "Run an external analyzer `command` that reads the graph as JSON on stdin and writes nodes, links and attributes to add as JSON to stdout, may be repeated")
+	fs.StringVar(&opts.Vulns, "vulns", "", "Mark the nodes reaching vulnerable symbols found in a `file` of govulncheck -json output")
+	fs.BoolVar(&opts.TestedBy, "tested-by", false, "Record the test functions exercising every node in its testedBy field")
+	fs.BoolVar(&opts.Centrality, "centrality", false, "Compute the PageRank and betweenness centrality of every node, which is slow for large graphs")
+	fs.BoolVar(&opts.ShortIDs, "short-ids", false, "Replace node IDs by short hashes and add a table of the full IDs, reducing output size")
+	fs.BoolVar(&opts.Strict, "strict", false, "Fail with a report of all load and type errors instead of marking nodes of broken packages")
+	fs.BoolFunc("progress", "Report the progress of the analysis on stderr, e.g. the number of packages analyzed", func(s string) error {
+		ok, err := strconv.ParseBool(s)
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+		opts.Progress = nil
+		if ok {
+			opts.Progress = os.Stderr
 		}
+		return nil
+	})
+	fs.Var((*overlayFlag)(&opts.Overlay), "overlay", "Replace source files by the files given in a JSON `file` of the form {\"Replace\": {\"path\": \"replacement\"}}, as for go build -overlay")
+	fs.StringVar(&opts.Rev, "rev", "", "Analyze the packages at a git `revision`, checked out into a temporary worktree")
+	fs.StringVar(&opts.ChangedSince, "changed-since", "", "Analyze only the packages changed since a git `revision`, including uncommitted changes, and the packages depending on them")
+	fs.Var((*shardFlag)(&opts.Shard), "shard", "Analyze only the packages of shard `i/n`, one of n disjoint sets of the packages, to analyze them in parallel processes")
+	fs.Var((*depthFlag)(&opts.IncludeDeps), "include-deps", "Include symbols of third-party dependencies up to `N` import hops away (-include-deps is -include-deps=1)")
+	return &opts
+}
+
+// depthFlag is an integer flag that can also be given without a value, which
+// sets it to 1.
+type depthFlag int
 
-		if *jsonMode {
-			jsonData, err = json.MarshalIndent(graph, "", "  ")
-		} else {
-			jsonData, err = json.Marshal(graph)
+func (d *depthFlag) String() string {
+	if d == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*d))
+}
+
+func (d *depthFlag) Set(s string) error {
+	switch s {
+	case "true":
+		*d = 1
+	case "false":
+		*d = 0
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid depth %q", s)
 		}
+		*d = depthFlag(n)
+	}
+	return nil
+}
+
+func (d *depthFlag) IsBoolFlag() bool { return true }
+
+// overlayFlag reads the file contents of an overlay in the format of go
+// build -overlay
+type overlayFlag map[string][]byte
+
+func (o *overlayFlag) String() string {
+	return ""
+}
+
+func (o *overlayFlag) Set(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var overlay struct {
+		Replace map[string]string
+	}
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	*o = make(overlayFlag, len(overlay.Replace))
+	for file, replacement := range overlay.Replace {
+		if replacement == "" {
+			return fmt.Errorf("%s: deleting %s is not supported", path, file)
+		}
+		contents, err := os.ReadFile(replacement)
 		if err != nil {
-			log.Fatalf("JSON marshaling error: %v", err)
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if file, err = filepath.Abs(file); err != nil {
+			return err
 		}
+		(*o)[file] = contents
 	}
+	return nil
+}
 
-	if *jsonMode {
-		fmt.Println(string(jsonData))
-	} else {
-		html := generateHTML(string(jsonData))
+// shardFlag is a flag selecting a shard as "index/count"
+type shardFlag analysis.Shard
+
+func (s *shardFlag) String() string {
+	if s == nil || s.Count == 0 {
+		return ""
+	}
+	return analysis.Shard(*s).String()
+}
+
+func (s *shardFlag) Set(v string) error {
+	index, count, ok := strings.Cut(v, "/")
+	i, err1 := strconv.Atoi(index)
+	n, err2 := strconv.Atoi(count)
+	if !ok || err1 != nil || err2 != nil || n < 1 || i < 1 || i > n {
+		return fmt.Errorf("invalid shard %q, expected i/n with 1 <= i <= n", v)
+	}
+	*s = shardFlag{Index: i, Count: n}
+	return nil
+}
+
+// listFlag is a flag that can be given multiple times, collecting its values
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// classifyFlag loads the classification rules of a YAML file on every use,
+// appending their classifiers
+type classifyFlag []analysis.Classifier
+
+// classifyFile is the format of -classify files
+type classifyFile struct {
+	Groups []analysis.ClassifyRule `yaml:"groups"`
+}
+
+func (c *classifyFlag) String() string {
+	return ""
+}
+
+func (c *classifyFlag) Set(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file classifyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return c.add(path, file.Groups)
+}
 
-		http.HandleFunc("/d3.js", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
-			w.Header().Set("Cache-Control", "max-age=604800")
-			w.Write([]byte(d3))
-		})
+// add appends the classifiers of the rules read from path
+func (c *classifyFlag) add(path string, rules []analysis.ClassifyRule) error {
+	for i, r := range rules {
+		classifier, err := r.Classifier()
+		if err != nil {
+			return fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+		}
+		*c = append(*c, classifier)
+	}
+	return nil
+}
 
-		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
-			w.Header().Set("Cross-Origin-Embedder-Policy", "require-corp")
-			w.Write([]byte(html))
-		})
+// trimPrefixFlag is a string flag that can also be given without a value, in
+// which case the paths of the analyzed modules are trimmed.
+type trimPrefixFlag analysis.TrimPrefix
 
-		fmt.Fprintf(os.Stderr, "Serving visualization at http://localhost:%s\n", *port)
-		log.Fatal(http.ListenAndServe(":"+*port, nil))
+func (f *trimPrefixFlag) String() string {
+	if f == nil {
+		return ""
 	}
+	return f.Prefix
 }
 
-//go:embed d3.v7.min.js
-var d3 string
+func (f *trimPrefixFlag) Set(s string) error {
+	switch s {
+	case "true":
+		f.Enabled, f.Prefix = true, ""
+	case "false":
+		f.Enabled, f.Prefix = false, ""
+	default:
+		f.Enabled, f.Prefix = true, strings.TrimSuffix(s, "/")
+	}
+	return nil
+}
 
-//go:embed viz.html
-var html string
+func (f *trimPrefixFlag) IsBoolFlag() bool { return true }
 
-// generateHTML takes JSON data as a string and embeds it in the HTML
-func generateHTML(jsonData string) string {
-	return strings.Replace(html, "DATA_PLACEHOLDER", jsonData, 1)
+// loadGraphJSON analyzes the given package paths and returns the graph as
+// JSON. If no paths are given, previously exported graph data is read from
+// stdin instead.
+func loadGraphJSON(opts *analysis.Options, paths []string) ([]byte, error) {
+	if len(paths) == 0 && !opts.Workspace {
+		fmt.Fprintln(os.Stderr, "Reading graph data from stdin...")
+		jsonData, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read JSON from stdin: %w", err)
+		}
+		return jsonData, nil
+	}
+
+	// Only the JSON is needed, not the packages the graph was built from
+	opts.Compact = true
+	g, err := analysis.Analyze(opts, paths...)
+	if err != nil {
+		return nil, err
+	}
+	summaries, collapsed := 0, 0
+	for _, node := range g.Nodes {
+		if node.Kind == graph.KindSummary {
+			summaries++
+			collapsed += len(node.Members)
+		}
+	}
+	if summaries > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: collapsed %d declarations into %d summary nodes to stay below -max-nodes %d\n", collapsed, summaries, opts.MaxNodes)
+	}
+
+	jsonData, err := json.Marshal(g)
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshaling error: %w", err)
+	}
+	return jsonData, nil
+}
+
+// exportCommand writes a single self-contained HTML file with the D3 bundle
+// and the graph data inlined, so it can be opened without the server.
+func exportCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.String("o", "graph.html", "Output file")
+	opts := addAnalyzeFlags(fs)
+	maxNodes := addViewFlags(fs, opts)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope export [-o graph.html] [<package-path>...]")
+		fmt.Fprintln(fs.Output(), "  Omit package paths to read graph data from stdin")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		opts.MaxNodes = *maxNodes
+
+		jsonData, err := loadGraphJSON(opts, fs.Args())
+		if err != nil {
+			return err
+		}
+
+		if err := render.WriteOutput(*output, "html", jsonData); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", *output)
+		return nil
+	}
 }
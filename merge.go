@@ -0,0 +1,55 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/phyrog/sgope/render"
+)
+
+// mergeCommand combines graph JSON files, e.g. of several services or of the
+// shards of an analysis, into the graph of the whole system.
+func mergeCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	format := fs.String("format", "json", "Output format ("+strings.Join(render.FormatNames(), ", ")+")")
+	output := fs.String("o", "-", "Output file, '-' for stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope merge [-format json|html] [-o file] <a.json> <b.json> [<c.json>...]")
+		fmt.Fprintln(fs.Output(), "  Nodes with the same ID are merged, links with the same endpoints and kind keep the higher weight")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() < 2 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if _, ok := render.Formats[*format]; !ok {
+			return fmt.Errorf("unknown format %q (available: %s)", *format, strings.Join(render.FormatNames(), ", "))
+		}
+
+		merged, err := readGraph(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		for _, path := range fs.Args()[1:] {
+			g, err := readGraph(path)
+			if err != nil {
+				return err
+			}
+			merged.Merge(g)
+		}
+		// Links of every graph add to the fan-in and fan-out of the others
+		merged.AddFanMetrics()
+
+		jsonData, err := json.Marshal(merged)
+		if err != nil {
+			return fmt.Errorf("JSON marshaling error: %w", err)
+		}
+		return render.WriteOutput(*output, *format, jsonData)
+	}
+}
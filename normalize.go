@@ -0,0 +1,88 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// fmtCommand writes graph files in canonical form, so graphs committed as
+// architecture baselines produce minimal diffs.
+func fmtCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "Write the result to the files instead of stdout")
+	list := fs.Bool("l", false, "List the files whose formatting differs from the canonical form")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope fmt [-w] [-l] [<graph.json>...]")
+		fmt.Fprintln(fs.Output(), "  Omit the files to format graph data from stdin")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 {
+			if *write || *list {
+				return fmt.Errorf("-w and -l need files")
+			}
+			g, err := readGraphFrom("stdin", os.Stdin)
+			if err != nil {
+				return err
+			}
+			data, err := formatGraph(g)
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+
+		for _, path := range fs.Args() {
+			original, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			g, err := readGraphFrom(path, bytes.NewReader(original))
+			if err != nil {
+				return err
+			}
+			data, err := formatGraph(g)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if *list && !bytes.Equal(data, original) {
+				fmt.Println(path)
+			}
+			if *write {
+				if !bytes.Equal(data, original) {
+					if err := os.WriteFile(path, data, 0o644); err != nil {
+						return err
+					}
+				}
+			} else if !*list {
+				if _, err := os.Stdout.Write(data); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// formatGraph returns the canonical indented JSON of g
+func formatGraph(g *graph.Graph) ([]byte, error) {
+	g.Normalize()
+	jsonData, err := json.Marshal(g)
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshaling error: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := render.WriteJSON(&buf, jsonData); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
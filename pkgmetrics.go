@@ -0,0 +1,176 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// packageMetrics are Robert Martin's package design metrics
+type packageMetrics struct {
+	Pkg string `json:"pkg"`
+	// Afferent is the number of other packages depending on the package
+	Afferent int `json:"afferent"`
+	// Efferent is the number of other packages the package depends on
+	Efferent int `json:"efferent"`
+	// Instability is Efferent / (Afferent + Efferent), from 0 for packages
+	// that are only depended on to 1 for packages that only depend on others
+	Instability float64 `json:"instability"`
+	// Abstractness is the fraction of the package's types that are
+	// interfaces
+	Abstractness float64 `json:"abstractness"`
+	// Distance is the distance from the main sequence A + I = 1. Packages
+	// far from it are either concrete and hard to change or abstract and
+	// unused.
+	Distance float64 `json:"distance"`
+}
+
+// metricsCommand reports the coupling, instability and abstractness of the
+// analyzed packages.
+func metricsCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("metrics", flag.ExitOnError)
+	jsonMode := fs.Bool("json", false, "Output the metrics as JSON")
+	typesMode := fs.Bool("types", false, "Report struct types with low cohesion (LCOM4 > 1) instead of package metrics")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope metrics [-json] [-types] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		if *typesMode {
+			return reportCohesion(g, *jsonMode)
+		}
+		metrics := measurePackages(g)
+
+		if *jsonMode {
+			jsonData, err := json.Marshal(metrics)
+			if err != nil {
+				return fmt.Errorf("JSON marshaling error: %w", err)
+			}
+			return render.WriteJSON(os.Stdout, jsonData)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "Ca\tCe\tI\tA\tD\t\t")
+		for _, m := range metrics {
+			fmt.Fprintf(w, "%d\t%d\t%.2f\t%.2f\t%.2f\t\t%s\n", m.Afferent, m.Efferent, m.Instability, m.Abstractness, m.Distance, m.Pkg)
+		}
+		return w.Flush()
+	}
+}
+
+// measurePackages computes the metrics of every analyzed package from the
+// links between the packages' nodes, sorted by package path. Test and
+// external nodes only count as dependents and dependencies.
+func measurePackages(g *graph.Graph) []packageMetrics {
+	afferent := make(map[string]map[string]bool)
+	efferent := make(map[string]map[string]bool)
+	for _, link := range g.Links {
+		from, to := g.Nodes[link.From], g.Nodes[link.To]
+		if from == nil || to == nil || from.Pkg == to.Pkg {
+			continue
+		}
+		if afferent[to.Pkg] == nil {
+			afferent[to.Pkg] = make(map[string]bool)
+		}
+		afferent[to.Pkg][from.Pkg] = true
+		if efferent[from.Pkg] == nil {
+			efferent[from.Pkg] = make(map[string]bool)
+		}
+		efferent[from.Pkg][to.Pkg] = true
+	}
+
+	types := make(map[string]int)
+	interfaces := make(map[string]int)
+	pkgs := make(map[string]bool)
+	for _, node := range g.Nodes {
+		if node.External || node.Test || node.Kind == graph.KindPackage || node.Kind == graph.KindFile || node.Kind == graph.KindComponent {
+			continue
+		}
+		pkgs[node.Pkg] = true
+		if node.Kind == graph.KindType && node.Parent == "" {
+			types[node.Pkg]++
+			if node.Type == graph.TypeInterface {
+				interfaces[node.Pkg]++
+			}
+		}
+	}
+
+	var metrics []packageMetrics
+	for pkg := range pkgs {
+		m := packageMetrics{
+			Pkg:      pkg,
+			Afferent: len(afferent[pkg]),
+			Efferent: len(efferent[pkg]),
+		}
+		if total := m.Afferent + m.Efferent; total > 0 {
+			m.Instability = float64(m.Efferent) / float64(total)
+		}
+		if types[pkg] > 0 {
+			m.Abstractness = float64(interfaces[pkg]) / float64(types[pkg])
+		}
+		m.Distance = math.Abs(m.Abstractness + m.Instability - 1)
+		metrics = append(metrics, m)
+	}
+	slices.SortFunc(metrics, func(a, b packageMetrics) int {
+		return cmp.Compare(a.Pkg, b.Pkg)
+	})
+	return metrics
+}
+
+// typeCohesion lists the method groups of a struct type with low cohesion
+type typeCohesion struct {
+	Type   string     `json:"type"`
+	LCOM   int        `json:"lcom"`
+	Groups [][]string `json:"groups"`
+}
+
+// reportCohesion prints the struct types whose methods fall into several
+// groups that share no fields, most groups first.
+func reportCohesion(g *graph.Graph, jsonMode bool) error {
+	var types []typeCohesion
+	for _, group := range g.MethodGroups() {
+		if len(group.Groups) > 1 {
+			types = append(types, typeCohesion{group.TypeID, len(group.Groups), group.Groups})
+		}
+	}
+	slices.SortFunc(types, func(a, b typeCohesion) int {
+		return cmp.Or(cmp.Compare(b.LCOM, a.LCOM), cmp.Compare(a.Type, b.Type))
+	})
+
+	if jsonMode {
+		jsonData, err := json.Marshal(types)
+		if err != nil {
+			return fmt.Errorf("JSON marshaling error: %w", err)
+		}
+		return render.WriteJSON(os.Stdout, jsonData)
+	}
+	for _, t := range types {
+		fmt.Printf("%s: LCOM %d\n", t.Type, t.LCOM)
+		for i, methods := range t.Groups {
+			fmt.Printf("\t%d: %s\n", i+1, strings.Join(methods, ", "))
+		}
+	}
+	return nil
+}
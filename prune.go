@@ -0,0 +1,110 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// pruneCommand applies the filters and collapsing of the analysis flags to an
+// existing graph file, so changing the view of a graph does not require
+// analyzing the packages again.
+func pruneCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	format := fs.String("format", "json", "Output format ("+strings.Join(render.FormatNames(), ", ")+")")
+	output := fs.String("o", "-", "Output file, '-' for stdout")
+	var dropKinds, focus listFlag
+	fs.Var(&dropKinds, "drop-kind", "Leave out the nodes of a `kind` or type, e.g. const, method or field, may be repeated")
+	var include, exclude *regexp.Regexp
+	fs.Func("include", "Only keep the declarations whose ID or package path matches a regular `expression`", func(s string) (err error) {
+		include, err = regexp.Compile(s)
+		return err
+	})
+	fs.Func("exclude", "Leave out the declarations whose ID or package path matches a regular `expression`, e.g. /vendor/|_mock", func(s string) (err error) {
+		exclude, err = regexp.Compile(s)
+		return err
+	})
+	exported := fs.Bool("exported", false, "Only keep exported declarations, i.e. the public API")
+	excludeGenerated := fs.Bool("exclude-generated", false, "Leave out declarations in generated files")
+	excludeTests := fs.Bool("exclude-tests", false, "Leave out declarations in test files")
+	fs.Var(&focus, "focus", "Only keep the declaration with the given `ID` and its neighborhood up to -depth links away, may be repeated")
+	depth := fs.Int("depth", 1, "Number of links to follow from the -focus declarations in either direction, -1 for everything connected to them")
+	condense := fs.Bool("condense", false, "Collapse every dependency cycle into a single component node")
+	reduce := fs.Bool("reduce", false, "Remove links implied by transitivity")
+	minWeight := fs.Int("min-weight", 0, "Leave out links with a weight below `N`")
+	topLinks := fs.Int("top-links", 0, "Only keep the `K` heaviest links of every node, in both directions, 0 for all")
+	maxNodes := fs.Int("max-nodes", 0, "Collapse leaves and then whole packages into summary nodes when the graph has more than `N` nodes, 0 for no limit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope prune [-drop-kind kind] [-include regexp] [-exclude regexp] [-focus id] [-max-nodes N] [-format json|html] [-o file] [<graph.json>]")
+		fmt.Fprintln(fs.Output(), "  Omit the file to read graph data from stdin")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if _, ok := render.Formats[*format]; !ok {
+			return fmt.Errorf("unknown format %q (available: %s)", *format, strings.Join(render.FormatNames(), ", "))
+		}
+		var g *graph.Graph
+		var err error
+		switch fs.NArg() {
+		case 0:
+			g, err = readGraphFrom("stdin", os.Stdin)
+		case 1:
+			g, err = readGraph(fs.Arg(0))
+		default:
+			fs.Usage()
+			os.Exit(2)
+		}
+		if err != nil {
+			return err
+		}
+
+		var kinds []string
+		for _, kind := range dropKinds {
+			kinds = append(kinds, strings.Split(kind, ",")...)
+		}
+		g.Filter(func(node *graph.Node) bool {
+			switch {
+			case slices.Contains(kinds, node.Kind), slices.Contains(kinds, node.Type),
+				include != nil && !include.MatchString(node.Id) && !include.MatchString(node.Pkg),
+				exclude != nil && (exclude.MatchString(node.Id) || exclude.MatchString(node.Pkg)),
+				*exported && !node.Exported && node.Kind != graph.KindPackage && node.Kind != graph.KindFile,
+				*excludeGenerated && node.Generated,
+				*excludeTests && node.Test:
+				return false
+			}
+			return true
+		})
+		if *condense {
+			g.Condense()
+		}
+		if *reduce {
+			g.Reduce()
+		}
+		g.AddFanMetrics()
+		if len(focus) > 0 {
+			for _, nodeID := range focus {
+				if _, ok := g.Nodes[nodeID]; !ok {
+					return fmt.Errorf("unknown focus node %q", nodeID)
+				}
+			}
+			g = g.Subgraph(focus, *depth)
+		}
+		g.PruneLinks(*minWeight, *topLinks)
+		g.Collapse(*maxNodes)
+
+		jsonData, err := json.Marshal(g)
+		if err != nil {
+			return fmt.Errorf("JSON marshaling error: %w", err)
+		}
+		return render.WriteOutput(*output, *format, jsonData)
+	}
+}
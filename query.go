@@ -0,0 +1,93 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// queryResult is a node with the links from and to it
+type queryResult struct {
+	Node   *graph.Node  `json:"node"`
+	Uses   []graph.Link `json:"uses"`
+	UsedBy []graph.Link `json:"usedBy"`
+}
+
+// queryCommand prints a symbol with what it depends on and what depends on it,
+// from the analyzed packages or a graph file.
+func queryCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	graphFile := fs.String("graph", "", "Read the graph from a JSON `file` instead of analyzing packages")
+	jsonMode := fs.Bool("json", false, "Output the node and its links as JSON")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope query [-graph graph.json] [-json] <symbol> [<package-path>...]")
+		fmt.Fprintln(fs.Output(), "  Symbols are given by node ID or by a unique suffix of it, e.g. pkg.Foo or Foo")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 || fs.NArg() == 1 && *graphFile == "" && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		var g *graph.Graph
+		var err error
+		if *graphFile != "" {
+			g, err = readGraph(*graphFile)
+		} else {
+			g, err = analysis.Analyze(opts, fs.Args()[1:]...)
+		}
+		if err != nil {
+			return err
+		}
+		node, err := g.FindNode(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		result := queryResult{Node: node, Uses: []graph.Link{}, UsedBy: []graph.Link{}}
+		for _, link := range g.Links {
+			if link.From == node.Id {
+				result.Uses = append(result.Uses, link)
+			}
+			if link.To == node.Id {
+				result.UsedBy = append(result.UsedBy, link)
+			}
+		}
+		byNode := func(id func(graph.Link) string) func(a, b graph.Link) int {
+			return func(a, b graph.Link) int {
+				return cmp.Or(cmp.Compare(id(a), id(b)), cmp.Compare(a.Kind, b.Kind))
+			}
+		}
+		slices.SortFunc(result.Uses, byNode(func(link graph.Link) string { return link.To }))
+		slices.SortFunc(result.UsedBy, byNode(func(link graph.Link) string { return link.From }))
+
+		if *jsonMode {
+			jsonData, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("JSON marshaling error: %w", err)
+			}
+			return render.WriteJSON(os.Stdout, jsonData)
+		}
+		fmt.Printf("%s: %s %s\n", nodePosition(node), nodeKind(node), node.Id)
+		fmt.Printf("Uses (%d):\n", len(result.Uses))
+		for _, link := range result.Uses {
+			fmt.Printf("\t[%s x%d] %s\n", link.Kind, link.Weight, link.To)
+		}
+		fmt.Printf("Used by (%d):\n", len(result.UsedBy))
+		for _, link := range result.UsedBy {
+			fmt.Printf("\t[%s x%d] %s\n", link.Kind, link.Weight, link.From)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+// Package render writes graph JSON data as indented JSON or as the
+// interactive HTML visualization, and serves the visualization over HTTP.
+package render
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Format writes graph JSON data to w in a specific format.
+type Format func(w io.Writer, jsonData []byte) error
+
+// Formats is the registry of formats selectable with -format. New formats
+// only need to be added here.
+var Formats = map[string]Format{
+	"json": WriteJSON,
+	"html": WriteHTML,
+}
+
+// FormatNames returns the names of the registered formats, sorted
+func FormatNames() []string {
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// WriteOutput writes jsonData in the given format to path, or to stdout if
+// path is empty or "-".
+func WriteOutput(path, format string, jsonData []byte) error {
+	write, ok := Formats[format]
+	if !ok {
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	if path == "" || path == "-" {
+		return write(os.Stdout, jsonData)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, jsonData); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteJSON writes jsonData to w, indented
+func WriteJSON(w io.Writer, jsonData []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, jsonData, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// WriteHTML writes a single self-contained HTML page visualizing jsonData,
+// with the D3 bundle inlined so it can be opened without the server
+func WriteHTML(w io.Writer, jsonData []byte) error {
+	_, err := io.WriteString(w, generateStandaloneHTML(string(jsonData)))
+	return err
+}
+
+// Handler returns an HTTP handler serving the visualization of jsonData at
+// / and the D3 bundle it loads at /d3.js.
+func Handler(jsonData []byte) http.Handler {
+	html := generateHTML(string(jsonData))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/d3.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "max-age=604800")
+		w.Write([]byte(d3))
+	})
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
+		w.Header().Set("Cross-Origin-Embedder-Policy", "require-corp")
+		w.Write([]byte(html))
+	})
+	return mux
+}
+
+//go:embed d3.v7.min.js
+var d3 string
+
+//go:embed viz.html
+var html string
+
+// d3ScriptTag is the tag in viz.html that loads the D3 bundle from the server
+const d3ScriptTag = `<script src="/d3.js"></script>`
+
+// generateHTML takes JSON data as a string and embeds it in the HTML
+func generateHTML(jsonData string) string {
+	return strings.Replace(html, "DATA_PLACEHOLDER", jsonData, 1)
+}
+
+// generateStandaloneHTML is like generateHTML but also inlines the D3 bundle
+func generateStandaloneHTML(jsonData string) string {
+	out := strings.Replace(html, d3ScriptTag, "<script>\n"+d3+"\n</script>", 1)
+	return strings.Replace(out, "DATA_PLACEHOLDER", jsonData, 1)
+}
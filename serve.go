@@ -0,0 +1,202 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serveOptions are the flags of the HTTP server of the visualization
+type serveOptions struct {
+	listen string
+	// tlsCert and tlsKey are the files of the certificate to serve HTTPS
+	// with, selfSigned makes serve generate one
+	tlsCert, tlsKey string
+	selfSigned      bool
+	// basicAuth is the user:password of basic authentication and token the
+	// bearer token required by the server, if set
+	basicAuth, token string
+}
+
+// tokenCookie is the cookie keeping the token of browsers, which cannot send
+// bearer tokens when loading a page
+const tokenCookie = "sgope_token"
+
+// addServeFlags registers the flags of the HTTP server on fs
+func addServeFlags(fs *flag.FlagSet) *serveOptions {
+	o := &serveOptions{listen: "localhost:8080"}
+	fs.StringVar(&o.listen, "listen", o.listen, "Serve the visualization on `address`, e.g. :8080 for all interfaces or localhost:0 for a free port")
+	fs.Func("port", "Serve the visualization on `port` on all interfaces, same as -listen :port", func(port string) error {
+		o.listen = ":" + port
+		return nil
+	})
+	fs.StringVar(&o.tlsCert, "tls-cert", "", "Serve HTTPS with the certificate in a PEM `file`, requires -tls-key")
+	fs.StringVar(&o.tlsKey, "tls-key", "", "PEM `file` with the private key of -tls-cert")
+	fs.BoolVar(&o.selfSigned, "tls-self-signed", false, "Serve HTTPS with a generated self-signed certificate, for development")
+	fs.StringVar(&o.basicAuth, "basic-auth", "", "Require HTTP basic authentication with `user:password` (default $SGOPE_BASIC_AUTH)")
+	fs.StringVar(&o.token, "token", "", "Require a bearer `token`, which browsers pass once in the printed URL (default $SGOPE_TOKEN)")
+	return o
+}
+
+// check fills in the credentials from the environment and reports invalid
+// flags, before serve is called after a possibly long analysis
+func (o *serveOptions) check() error {
+	if o.basicAuth == "" {
+		o.basicAuth = os.Getenv("SGOPE_BASIC_AUTH")
+	}
+	if o.token == "" {
+		o.token = os.Getenv("SGOPE_TOKEN")
+	}
+	switch {
+	case o.basicAuth != "" && !strings.Contains(o.basicAuth, ":"):
+		return fmt.Errorf("invalid -basic-auth, expected user:password")
+	case o.selfSigned && (o.tlsCert != "" || o.tlsKey != ""):
+		return fmt.Errorf("-tls-self-signed cannot be combined with -tls-cert and -tls-key")
+	case (o.tlsCert == "") != (o.tlsKey == ""):
+		return fmt.Errorf("-tls-cert and -tls-key must be given together")
+	}
+	return nil
+}
+
+// serve serves handler on the address of o and prints its URL, with the
+// port chosen by the system for port 0
+func (o *serveOptions) serve(handler http.Handler) error {
+	host, _, _ := net.SplitHostPort(o.listen)
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	if err := o.check(); err != nil {
+		return err
+	}
+	server := &http.Server{Handler: o.authenticate(handler)}
+	switch {
+	case o.tlsCert != "":
+		cert, err := tls.LoadX509KeyPair(o.tlsCert, o.tlsKey)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	case o.selfSigned:
+		cert, err := selfSignedCertificate(host)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Using a self-signed certificate with SHA-256 fingerprint %X\n", sha256.Sum256(cert.Certificate[0]))
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	l, err := net.Listen("tcp", o.listen)
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if server.TLSConfig != nil {
+		scheme = "https"
+	}
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	query := ""
+	if o.token != "" {
+		query = "/?token=" + url.QueryEscape(o.token)
+	}
+	fmt.Fprintf(os.Stderr, "Serving visualization at %s://%s%s\n", scheme, net.JoinHostPort(host, port), query)
+	if server.TLSConfig != nil {
+		return server.ServeTLS(l, "", "")
+	}
+	return server.Serve(l)
+}
+
+// authenticate wraps handler to require the basic authentication or token
+// of o, if any
+func (o *serveOptions) authenticate(handler http.Handler) http.Handler {
+	if o.basicAuth == "" && o.token == "" {
+		return handler
+	}
+	user, password, _ := strings.Cut(o.basicAuth, ":")
+	equal := func(a, b string) bool {
+		return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.token != "" {
+			if token := r.URL.Query().Get("token"); token != "" && equal(token, o.token) {
+				// Keep the token in a cookie for the page and its scripts,
+				// and remove it from the URL in the browser history
+				http.SetCookie(w, &http.Cookie{
+					Name:     tokenCookie,
+					Value:    token,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteStrictMode,
+				})
+				http.Redirect(w, r, r.URL.Path, http.StatusFound)
+				return
+			}
+			if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && equal(bearer, o.token) {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			if cookie, err := r.Cookie(tokenCookie); err == nil && equal(cookie.Value, o.token) {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		if o.basicAuth != "" {
+			if u, p, ok := r.BasicAuth(); ok && equal(u, user) && equal(p, password) {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="sgope", charset="UTF-8"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// selfSignedCertificate generates a certificate for localhost and host,
+// valid for 30 days
+func selfSignedCertificate(host string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"sgope"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(30 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else if host != "localhost" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
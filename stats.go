@@ -0,0 +1,161 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// statsCommand prints a summary of the graph: counts of nodes and links,
+// density, degree distribution, diameter and the size of the largest strongly
+// connected component.
+func statsCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope stats <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+
+		nodeKinds := make(map[string]int)
+		pkgNodes := make(map[string]int)
+		for _, node := range g.Nodes {
+			nodeKinds[nodeKind(node)]++
+			pkgNodes[node.Pkg]++
+		}
+		linkKinds := make(map[string]int)
+		pkgLinks := make(map[string]int)
+		for _, link := range g.Links {
+			linkKinds[link.Kind]++
+			if node, ok := g.Nodes[link.From]; ok {
+				pkgLinks[node.Pkg]++
+			}
+		}
+
+		succ := g.Successors()
+		pairs := 0
+		degree := make(map[string]int)
+		for from, tos := range succ {
+			pairs += len(tos)
+			degree[from] += len(tos)
+			for _, to := range tos {
+				degree[to]++
+			}
+		}
+		density := 0.0
+		if n := len(g.Nodes); n > 1 {
+			density = float64(pairs) / float64(n*(n-1))
+		}
+		largestSCC := 0
+		for _, component := range graph.StronglyConnected(g.Nodes, succ) {
+			largestSCC = max(largestSCC, len(component))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "Nodes:\t%d\n", len(g.Nodes))
+		for _, kind := range slices.Sorted(maps.Keys(nodeKinds)) {
+			fmt.Fprintf(w, "  %s\t%d\n", kind, nodeKinds[kind])
+		}
+		fmt.Fprintf(w, "Links:\t%d\n", len(g.Links))
+		for _, kind := range slices.Sorted(maps.Keys(linkKinds)) {
+			fmt.Fprintf(w, "  %s\t%d\n", kind, linkKinds[kind])
+		}
+		fmt.Fprintf(w, "Packages:\t%d\n", len(pkgNodes))
+		for _, pkg := range slices.Sorted(maps.Keys(pkgNodes)) {
+			fmt.Fprintf(w, "  %s\t%d nodes, %d links\n", pkg, pkgNodes[pkg], pkgLinks[pkg])
+		}
+		fmt.Fprintf(w, "Density:\t%.4f\n", density)
+		fmt.Fprintf(w, "Degree:\t%s\n", degreeSummary(g, degree))
+		for _, bucket := range degreeHistogram(g, degree) {
+			fmt.Fprintf(w, "  %s\t%d\n", bucket.label, bucket.count)
+		}
+		fmt.Fprintf(w, "Diameter:\t%d\n", diameter(g, succ))
+		fmt.Fprintf(w, "Largest SCC:\t%d nodes\n", largestSCC)
+		return w.Flush()
+	}
+}
+
+// degreeSummary formats the minimum, median, mean and maximum number of
+// distinct neighbors of the nodes
+func degreeSummary(g *graph.Graph, degree map[string]int) string {
+	if len(g.Nodes) == 0 {
+		return "-"
+	}
+	var degrees []int
+	total := 0
+	for nodeID := range g.Nodes {
+		degrees = append(degrees, degree[nodeID])
+		total += degree[nodeID]
+	}
+	slices.Sort(degrees)
+	return fmt.Sprintf("min %d, median %d, mean %.1f, max %d",
+		degrees[0], degrees[len(degrees)/2], float64(total)/float64(len(degrees)), degrees[len(degrees)-1])
+}
+
+type degreeBucket struct {
+	label string
+	count int
+}
+
+// degreeHistogram counts the nodes by degree in power-of-two buckets
+func degreeHistogram(g *graph.Graph, degree map[string]int) []degreeBucket {
+	var buckets []degreeBucket
+	for nodeID := range g.Nodes {
+		d := degree[nodeID]
+		i, low, high := 0, 0, 0
+		for d > high {
+			i++
+			low, high = high+1, 2*high+1
+		}
+		for len(buckets) <= i {
+			buckets = append(buckets, degreeBucket{})
+		}
+		if low == high {
+			buckets[i].label = fmt.Sprint(low)
+		} else {
+			buckets[i].label = fmt.Sprintf("%d-%d", low, high)
+		}
+		buckets[i].count++
+	}
+	return slices.DeleteFunc(buckets, func(b degreeBucket) bool { return b.count == 0 })
+}
+
+// diameter returns the longest shortest path between any two nodes of g for
+// which a path exists, following the direction of links
+func diameter(g *graph.Graph, succ map[string][]string) int {
+	longest := 0
+	for start := range g.Nodes {
+		dist := map[string]int{start: 0}
+		queue := []string{start}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			longest = max(longest, dist[v])
+			for _, w := range succ[v] {
+				if _, ok := dist[w]; !ok {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+			}
+		}
+	}
+	return longest
+}
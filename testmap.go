@@ -0,0 +1,109 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// testsForCommand lists the test functions that exercise a symbol, directly or
+// transitively.
+func testsForCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("tests-for", flag.ExitOnError)
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope tests-for <symbol> <package-path> [<package-path>...]")
+		fmt.Fprintln(fs.Output(), "  Symbols are given by node ID or by a unique suffix of it, e.g. pkg.Foo or Foo")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() < 1 || fs.NArg() == 1 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()[1:]...)
+		if err != nil {
+			return err
+		}
+		node, err := g.FindNode(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		tests := g.TestsFor()[node.Id]
+		for _, testID := range tests {
+			fmt.Printf("%s: %s\n", nodePosition(g.Nodes[testID]), testID)
+		}
+		if len(tests) == 0 {
+			fmt.Fprintf(os.Stderr, "No tests exercise %s\n", node.Id)
+		}
+		return nil
+	}
+}
+
+// untestedCommand lists the exported functions and methods that no test
+// exercises.
+func untestedCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("untested", flag.ExitOnError)
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope untested <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+
+		untested, total := untestedNodes(g)
+		for _, node := range untested {
+			fmt.Printf("%s: exported %s %s is not exercised by any test\n", nodePosition(node), nodeKind(node), node.Id)
+		}
+		fmt.Fprintf(os.Stderr, "%d of %d exported functions and methods untested\n", len(untested), total)
+		return nil
+	}
+}
+
+// untestedNodes returns the exported functions and methods of the analyzed code
+// that are not reachable from any test function, sorted by position, and the
+// number of exported functions and methods.
+func untestedNodes(g *graph.Graph) (untested []*graph.Node, total int) {
+	var tests []string
+	for _, node := range g.Nodes {
+		if graph.IsTestNode(node) {
+			tests = append(tests, node.Id)
+		}
+	}
+	reachable := g.Reachability().From(tests)
+
+	for _, node := range g.Nodes {
+		if !node.Exported || node.Test || node.External || node.Kind != graph.KindFunc {
+			continue
+		}
+		if node.Type != graph.FuncBasic && node.Type != graph.FuncMethod {
+			continue
+		}
+		// Interface methods have no body to exercise
+		if parent, ok := g.Nodes[node.Parent]; ok && node.Type == graph.FuncMethod && parent.Type == graph.TypeInterface {
+			continue
+		}
+		total++
+		if !reachable[node.Id] {
+			untested = append(untested, node)
+		}
+	}
+	graph.SortByPosition(untested)
+	return untested, total
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/phyrog/sgope/analysis"
+)
+
+// topEntry holds the metrics of a symbol or package ranked by sgope top
+type topEntry struct {
+	name                  string
+	fanIn, fanOut         float64
+	lines, complexity     float64
+	pageRank, betweenness float64
+}
+
+// topMetrics are the metrics selectable with top -by
+var topMetrics = map[string]func(e topEntry) float64{
+	"fanin":       func(e topEntry) float64 { return e.fanIn },
+	"fanout":      func(e topEntry) float64 { return e.fanOut },
+	"loc":         func(e topEntry) float64 { return e.lines },
+	"complexity":  func(e topEntry) float64 { return e.complexity },
+	"pagerank":    func(e topEntry) float64 { return e.pageRank },
+	"betweenness": func(e topEntry) float64 { return e.betweenness },
+}
+
+// topCommand lists the symbols or packages with the highest fan-in, i.e. the
+// most depended on, and the highest fan-out, i.e. the most depending. With
+// -centrality the symbols with the highest PageRank and betweenness are listed
+// as well. With -by a single table of all metrics ranked by the given one is
+// printed instead.
+func topCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	n := fs.Int("n", 10, "Number of entries per list")
+	level := fs.String("level", levelSymbol, "Rank symbols or packages ("+levelSymbol+", "+levelPackage+")")
+	by := fs.String("by", "", "Print a single table ranked by a metric ("+strings.Join(slices.Sorted(maps.Keys(topMetrics)), ", ")+")")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope top [-n 10] [-by fanin|fanout|loc|complexity] [-level symbol|package] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if *level != levelSymbol && *level != levelPackage {
+			return fmt.Errorf("unknown level %q (available: %s, %s)", *level, levelSymbol, levelPackage)
+		}
+		if *by != "" {
+			if _, ok := topMetrics[*by]; !ok {
+				return fmt.Errorf("unknown metric %q (available: %s)", *by, strings.Join(slices.Sorted(maps.Keys(topMetrics)), ", "))
+			}
+			if *by == "pagerank" || *by == "betweenness" {
+				opts.Centrality = true
+			}
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+
+		var entries []topEntry
+		if *level == levelPackage {
+			packages := make(map[string]topEntry)
+			for _, node := range g.Nodes {
+				if node.External {
+					continue
+				}
+				e := packages[node.Pkg]
+				e.name = node.Pkg
+				e.fanIn, e.fanOut = float64(node.PkgFanIn), float64(node.PkgFanOut)
+				e.lines += float64(node.Lines)
+				e.complexity += float64(node.Complexity)
+				packages[node.Pkg] = e
+			}
+			entries = slices.Collect(maps.Values(packages))
+		} else {
+			for _, node := range g.Nodes {
+				if !node.External {
+					entries = append(entries, topEntry{
+						name:        node.Id,
+						fanIn:       float64(node.FanIn),
+						fanOut:      float64(node.FanOut),
+						lines:       float64(node.Lines),
+						complexity:  float64(node.Complexity),
+						pageRank:    node.PageRank,
+						betweenness: node.Betweenness,
+					})
+				}
+			}
+		}
+
+		rank := func(value func(e topEntry) float64) []topEntry {
+			slices.SortFunc(entries, func(a, b topEntry) int {
+				return cmp.Or(cmp.Compare(value(b), value(a)), cmp.Compare(a.name, b.name))
+			})
+			return entries[:min(*n, len(entries))]
+		}
+
+		if *by != "" {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+			header := "#\tFAN-IN\tFAN-OUT\tLOC\tCOMPLEXITY\t"
+			if opts.Centrality {
+				header += "PAGERANK\tBETWEENNESS\t"
+			}
+			fmt.Fprintln(w, header+"\tSYMBOL")
+			for i, e := range rank(topMetrics[*by]) {
+				fmt.Fprintf(w, "%d\t%g\t%g\t%g\t%g\t", i+1, e.fanIn, e.fanOut, e.lines, e.complexity)
+				if opts.Centrality {
+					fmt.Fprintf(w, "%.4g\t%.4g\t", e.pageRank, e.betweenness)
+				}
+				fmt.Fprintf(w, "\t%s\n", e.name)
+			}
+			return w.Flush()
+		}
+
+		list := func(title string, value func(e topEntry) float64) {
+			fmt.Println(title)
+			for _, e := range rank(value) {
+				if value(e) > 0 {
+					fmt.Printf("\t%8.4g  %s\n", value(e), e.name)
+				}
+			}
+		}
+		list("Most depended on (fan-in):", topMetrics["fanin"])
+		list("Most depending (fan-out):", topMetrics["fanout"])
+		if opts.Centrality && *level == levelSymbol {
+			list("Highest PageRank:", topMetrics["pagerank"])
+			list("Highest betweenness:", topMetrics["betweenness"])
+		}
+		return nil
+	}
+}
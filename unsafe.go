@@ -0,0 +1,107 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+	"github.com/phyrog/sgope/render"
+)
+
+// unsafeUse is a declaration using unsafe or cgo and the declarations
+// depending on it
+type unsafeUse struct {
+	Node     string          `json:"node"`
+	Position *graph.Position `json:"position,omitempty"`
+	Unsafe   bool            `json:"unsafe,omitempty"`
+	Cgo      bool            `json:"cgo,omitempty"`
+	Callers  []string        `json:"callers,omitempty"`
+}
+
+// unsafeCommand lists the declarations whose bodies use unsafe or cgo, and
+// optionally the declarations depending on them, for auditing memory-unsafe
+// code.
+func unsafeCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("unsafe", flag.ExitOnError)
+	callers := fs.Bool("callers", false, "Also list the declarations that depend on each one, directly or transitively")
+	jsonMode := fs.Bool("json", false, "Output the declarations as JSON")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope unsafe [-callers] [-json] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		uses := unsafeUses(g, *callers)
+
+		if *jsonMode {
+			if uses == nil {
+				uses = []unsafeUse{}
+			}
+			jsonData, err := json.Marshal(uses)
+			if err != nil {
+				return fmt.Errorf("JSON marshaling error: %w", err)
+			}
+			return render.WriteJSON(os.Stdout, jsonData)
+		}
+		for _, use := range uses {
+			var what []string
+			if use.Unsafe {
+				what = append(what, "unsafe")
+			}
+			if use.Cgo {
+				what = append(what, "cgo")
+			}
+			node := g.Nodes[use.Node]
+			fmt.Printf("%s: %s %s uses %s\n", nodePosition(node), nodeKind(node), node.Id, strings.Join(what, " and "))
+			for _, caller := range use.Callers {
+				fmt.Printf("\tused by %s\n", caller)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%d declarations use unsafe or cgo\n", len(uses))
+		return nil
+	}
+}
+
+// unsafeUses returns the nodes using unsafe or cgo sorted by position, with
+// the nodes depending on them if callers is set
+func unsafeUses(g *graph.Graph, callers bool) []unsafeUse {
+	var nodes []*graph.Node
+	for _, node := range g.Nodes {
+		if node.Unsafe || node.Cgo {
+			nodes = append(nodes, node)
+		}
+	}
+	graph.SortByPosition(nodes)
+
+	callersOf := make(map[string][]string)
+	if callers {
+		for from, targets := range g.UnsafeDependents() {
+			for _, target := range targets {
+				callersOf[target] = append(callersOf[target], from)
+			}
+		}
+	}
+	uses := make([]unsafeUse, 0, len(nodes))
+	for _, node := range nodes {
+		callers := callersOf[node.Id]
+		slices.Sort(callers)
+		uses = append(uses, unsafeUse{node.Id, node.Position, node.Unsafe, node.Cgo, callers})
+	}
+	return uses
+}
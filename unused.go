@@ -0,0 +1,129 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// unusedExportsCommand lists the exported symbols that are not used outside of
+// their package, or only by tests.
+func unusedExportsCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("unused-exports", flag.ExitOnError)
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope unused-exports <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+
+		unused, testOnly := unusedExports(g)
+		for _, node := range unused {
+			fmt.Printf("%s: exported %s %s is not used outside its package\n", nodePosition(node), nodeKind(node), node.Id)
+		}
+		for _, node := range testOnly {
+			fmt.Printf("%s: exported %s %s is only used outside its package by tests\n", nodePosition(node), nodeKind(node), node.Id)
+		}
+		return nil
+	}
+}
+
+// unusedExports returns the exported declarations of the analyzed code that
+// no node of another package links to, and those that are only used by test
+// code of other packages. Uses of generic instances count for their origin.
+// Methods that implement an interface method are never reported, since they
+// may be called through the interface, and neither are the fields and
+// methods of types used through reflection.
+func unusedExports(g *graph.Graph) (unused, testOnly []*graph.Node) {
+	origin := make(map[string]string)
+	satisfies := make(map[string][]string)
+	methodNames := make(map[string]map[string]bool)
+	for _, node := range g.Nodes {
+		if node.Type == graph.FuncMethod {
+			if methodNames[node.Parent] == nil {
+				methodNames[node.Parent] = make(map[string]bool)
+			}
+			methodNames[node.Parent][graph.MethodName(node)] = true
+		}
+	}
+	for _, link := range g.Links {
+		switch link.Kind {
+		case graph.LinkInstantiates:
+			origin[link.From] = link.To
+		case graph.LinkImplements:
+			satisfies[link.From] = append(satisfies[link.From], link.To)
+		}
+	}
+
+	usedBy := make(map[string]bool)
+	testedBy := make(map[string]bool)
+	for _, link := range g.Links {
+		switch link.Kind {
+		case graph.LinkMethodOf, graph.LinkParent, graph.LinkImplements, graph.LinkInstantiates:
+			// Structural links do not use the target
+			continue
+		}
+		from, ok := g.Nodes[link.From]
+		if !ok {
+			continue
+		}
+		to := link.To
+		if o, ok := origin[to]; ok {
+			to = o
+		}
+		target, ok := g.Nodes[to]
+		if !ok || from.Pkg == target.Pkg {
+			continue
+		}
+		if from.Test {
+			testedBy[to] = true
+		} else {
+			usedBy[to] = true
+		}
+	}
+
+	for _, node := range g.Nodes {
+		if !node.Exported || node.External || node.Test || usedBy[node.Id] {
+			continue
+		}
+		if node.Kind == graph.KindPackage || node.Kind == graph.KindComponent || node.Type == graph.ConstGroup {
+			continue
+		}
+		if _, ok := origin[node.Id]; ok {
+			continue
+		}
+		// Reflection, e.g. encoding/json, uses the exported fields and
+		// methods of reflected types from other packages
+		if parent, ok := g.Nodes[node.Parent]; ok && parent.Reflected && (node.Type == graph.FuncMethod || node.Type == graph.VarField) {
+			continue
+		}
+		if node.Type == graph.FuncMethod && slices.ContainsFunc(satisfies[node.Parent], func(iface string) bool {
+			return methodNames[iface][graph.MethodName(node)]
+		}) {
+			continue
+		}
+		if testedBy[node.Id] {
+			testOnly = append(testOnly, node)
+		} else {
+			unused = append(unused, node)
+		}
+	}
+	graph.SortByPosition(unused)
+	graph.SortByPosition(testOnly)
+	return unused, testOnly
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/phyrog/sgope/graph"
+)
+
+// validationErrors lists the problems found in a graph file
+type validationErrors []string
+
+func (e validationErrors) Error() string {
+	return "invalid graph:\n\t" + strings.Join(e, "\n\t")
+}
+
+// validateCommand checks the structure and referential integrity of a JSON
+// graph file, so pipelines can detect incompatible or corrupted graphs.
+func validateCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope validate [<file.json>]")
+		fmt.Fprintln(fs.Output(), "  Omit the file to read graph data from stdin")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		var data []byte
+		var err error
+		name := "stdin"
+		switch fs.NArg() {
+		case 0:
+			data, err = io.ReadAll(os.Stdin)
+		case 1:
+			name = fs.Arg(0)
+			data, err = os.ReadFile(name)
+		default:
+			fs.Usage()
+			os.Exit(2)
+		}
+		if err != nil {
+			return err
+		}
+
+		nodes, links, err := validateGraph(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Printf("%s: valid graph with %d nodes and %d links\n", name, nodes, links)
+		return nil
+	}
+}
+
+// validateGraph checks JSON graph data and returns the number of nodes and
+// links in it
+func validateGraph(data []byte) (nodes, links int, err error) {
+	var g struct {
+		SchemaVersion *int `json:"schemaVersion"`
+		Nodes         []struct {
+			Id   string `json:"id"`
+			Kind string `json:"kind"`
+		} `json:"nodes"`
+		Links []graph.Link `json:"links"`
+	}
+	if err := json.Unmarshal(data, &g); err != nil {
+		return 0, 0, fmt.Errorf("malformed JSON: %w", err)
+	}
+
+	var errs validationErrors
+	switch {
+	case g.SchemaVersion == nil:
+		errs = append(errs, "missing schemaVersion")
+	case *g.SchemaVersion > graph.SchemaVersion:
+		errs = append(errs, fmt.Sprintf("unsupported schemaVersion %d, this version of sgope supports up to %d", *g.SchemaVersion, graph.SchemaVersion))
+	}
+
+	ids := make(map[string]bool, len(g.Nodes))
+	for i, node := range g.Nodes {
+		switch {
+		case node.Id == "":
+			errs = append(errs, fmt.Sprintf("node %d has no id", i))
+		case ids[node.Id]:
+			errs = append(errs, fmt.Sprintf("duplicate node %q", node.Id))
+		}
+		if node.Kind == "" {
+			errs = append(errs, fmt.Sprintf("node %q has no kind", node.Id))
+		}
+		ids[node.Id] = true
+	}
+
+	for _, link := range g.Links {
+		if !ids[link.From] {
+			errs = append(errs, fmt.Sprintf("link %s -> %s: unknown source node", link.From, link.To))
+		}
+		if !ids[link.To] {
+			errs = append(errs, fmt.Sprintf("link %s -> %s: unknown target node", link.From, link.To))
+		}
+	}
+
+	if len(errs) > 0 {
+		return 0, 0, errs
+	}
+	return len(g.Nodes), len(g.Links), nil
+}
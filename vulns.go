@@ -0,0 +1,110 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// vulnpathsCommand prints the dependency chains from the analyzed code to the
+// vulnerable symbols found by govulncheck.
+func vulnpathsCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("vulnpaths", flag.ExitOnError)
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope vulnpaths [-vulns govulncheck.json] <package-path> [<package-path>...]")
+		fmt.Fprintln(fs.Output(), "  Omit -vulns to run govulncheck -json on the packages")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if opts.Vulns == "" {
+			opts.Vulns = "-"
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()...)
+		if err != nil {
+			return err
+		}
+
+		paths := vulnPaths(g)
+		for _, osv := range slices.Sorted(maps.Keys(paths)) {
+			fmt.Printf("%s:\n", osv)
+			for _, path := range paths[osv] {
+				fmt.Printf("\t%s\n", strings.Join(path, " -> "))
+			}
+		}
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "No vulnerable symbols reached")
+		}
+		return nil
+	}
+}
+
+// vulnPaths returns, for every vulnerability, a shortest dependency chain
+// from each entry point of the analyzed code to a vulnerable symbol. Entry
+// points are the nodes reaching the vulnerability that no other node reaching
+// it depends on.
+func vulnPaths(g *graph.Graph) map[string][][]string {
+	targets := make(map[string][]string)
+	reaching := make(map[string]map[string]bool)
+	for _, node := range g.Nodes {
+		for _, osv := range node.Vulnerable {
+			targets[osv] = append(targets[osv], node.Id)
+		}
+		for _, osv := range node.Vulns {
+			if reaching[osv] == nil {
+				reaching[osv] = make(map[string]bool)
+			}
+			reaching[osv][node.Id] = true
+		}
+	}
+	hasDependent := make(map[string]map[string]bool)
+	for _, link := range g.Links {
+		to, ok := g.Nodes[link.To]
+		if !ok {
+			continue
+		}
+		for _, osv := range to.Vulns {
+			if reaching[osv][link.From] && link.From != link.To {
+				if hasDependent[osv] == nil {
+					hasDependent[osv] = make(map[string]bool)
+				}
+				hasDependent[osv][link.To] = true
+			}
+		}
+	}
+
+	succ := g.Successors()
+	paths := make(map[string][][]string)
+	for osv, nodes := range reaching {
+		slices.Sort(targets[osv])
+		for _, nodeID := range slices.Sorted(maps.Keys(nodes)) {
+			if hasDependent[osv][nodeID] || g.Nodes[nodeID].External {
+				continue
+			}
+			var shortest []string
+			for _, target := range targets[osv] {
+				paths := graph.ShortestPaths(succ, nodeID, target, false)
+				if len(paths) > 0 && (shortest == nil || len(paths[0]) < len(shortest)) {
+					shortest = paths[0]
+				}
+			}
+			if shortest != nil {
+				paths[osv] = append(paths[osv], shortest)
+			}
+		}
+	}
+	return paths
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/phyrog/sgope/render"
+)
+
+// watchCommand serves the visualization of packages like serve and analyzes
+// them again whenever their files change, so reloading the page shows the
+// current code. With -o the graph is written to a file instead.
+func watchCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	server := addServeFlags(fs)
+	format := fs.String("format", "html", "Format of the -o file ("+strings.Join(render.FormatNames(), ", ")+")")
+	output := fs.String("o", "", "Write the graph to a `file` on every change instead of serving the visualization")
+	interval := fs.Duration("interval", time.Second, "How often to check the files for changes")
+	opts := addAnalyzeFlags(fs)
+	maxNodes := addViewFlags(fs, opts)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope watch [-listen localhost:8080] [-o file [-format html|json]] <package-path> [<package-path>...]")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() == 0 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if _, ok := render.Formats[*format]; !ok {
+			return fmt.Errorf("unknown output format %q (available: %s)", *format, strings.Join(render.FormatNames(), ", "))
+		}
+		if err := server.check(); err != nil {
+			return err
+		}
+		if *output == "" || *format != "json" {
+			opts.MaxNodes = *maxNodes
+		}
+		// The cache loads the packages again once their files change
+		cache := &loadCache{}
+		opts.Load = cache.load
+
+		var current atomic.Pointer[http.Handler]
+		update := func() error {
+			jsonData, err := loadGraphJSON(opts, fs.Args())
+			if err != nil {
+				return err
+			}
+			if *output != "" {
+				if err := render.WriteOutput(*output, *format, jsonData); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Wrote %s\n", *output)
+				return nil
+			}
+			handler := render.Handler(jsonData)
+			current.Store(&handler)
+			return nil
+		}
+		if err := update(); err != nil {
+			return err
+		}
+
+		if *output == "" {
+			go func() {
+				log.Fatal(server.serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					(*current.Load()).ServeHTTP(w, r)
+				})))
+			}()
+		}
+		for range time.Tick(*interval) {
+			if !cache.changed() {
+				continue
+			}
+			fmt.Fprintln(os.Stderr, "Files changed, analyzing again...")
+			// Keep the previous graph while the code does not compile
+			if err := update(); err != nil {
+				log.Print(err)
+			}
+		}
+		return nil
+	}
+}
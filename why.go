@@ -0,0 +1,77 @@
+// SPDX-License-Identitfier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/phyrog/sgope/analysis"
+	"github.com/phyrog/sgope/graph"
+)
+
+// whyCommand explains why one symbol depends on another by printing the
+// shortest dependency paths between them.
+func whyCommand() (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet("why", flag.ExitOnError)
+	all := fs.Bool("all", false, "Print all shortest paths instead of one")
+	opts := addAnalyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sgope why [-all] <from> <to> <package-path> [<package-path>...]")
+		fmt.Fprintln(fs.Output(), "  Symbols are given by node ID or by a unique suffix of it, e.g. pkg.Foo or Foo")
+		fs.PrintDefaults()
+	}
+	return fs, func() error {
+		if fs.NArg() < 2 || fs.NArg() == 2 && !opts.Workspace {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		g, err := analysis.Analyze(opts, fs.Args()[2:]...)
+		if err != nil {
+			return err
+		}
+		from, err := g.FindNode(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		to, err := g.FindNode(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		paths := g.ShortestPaths(from.Id, to.Id, *all)
+		if len(paths) == 0 {
+			return fmt.Errorf("%s does not depend on %s", from.Id, to.Id)
+		}
+
+		kinds := make(map[graph.LinkKey][]string)
+		for _, link := range g.Links {
+			key := graph.LinkKey{From: link.From, To: link.To}
+			kinds[key] = append(kinds[key], link.Kind)
+		}
+		for i, path := range paths {
+			if len(paths) > 1 {
+				fmt.Printf("path %d:\n", i+1)
+			}
+			for j, nodeID := range path {
+				hop := g.Nodes[nodeID]
+				position := ""
+				if hop.Position != nil {
+					position = fmt.Sprintf(" (%s:%d)", hop.Position.File, hop.Position.StartLine)
+				}
+				if j == 0 {
+					fmt.Printf("\t%s%s\n", nodeID, position)
+					continue
+				}
+				linkKinds := kinds[graph.LinkKey{From: path[j-1], To: nodeID}]
+				slices.Sort(linkKinds)
+				fmt.Printf("\t-> [%s] %s%s\n", strings.Join(linkKinds, ","), nodeID, position)
+			}
+		}
+		return nil
+	}
+}